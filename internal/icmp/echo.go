@@ -30,8 +30,10 @@ func (p *Echo) Marshal() ([]byte, error) {
 	return b, nil
 }
 
-// parseEcho parses b as an ICMP echo request or reply message body.
-func parseEcho(b []byte) (*Echo, error) {
+// ParseEcho parses b as an ICMP echo request or reply message body,
+// which carries the identifier and the sequence number in network
+// byte order followed by the data.
+func ParseEcho(b []byte) (*Echo, error) {
 	bodyLen := len(b)
 	if bodyLen < 4 {
 		return nil, errors.New("message too short")
@@ -0,0 +1,32 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build gofuzz
+
+package icmp
+
+import "golang.org/x/net/internal/iana"
+
+// Fuzz is the entry point for go-fuzz.  It parses b as both ICMP for
+// IPv4 and ICMP for IPv6 messages, and checks that a successfully
+// parsed message survives a round trip through Marshal and
+// ParseMessage.
+func Fuzz(b []byte) int {
+	score := 0
+	for _, proto := range []int{iana.ProtocolICMP, iana.ProtocolIPv6ICMP} {
+		m, err := ParseMessage(proto, b)
+		if err != nil {
+			continue
+		}
+		wb, err := m.Marshal(nil)
+		if err != nil {
+			panic(err)
+		}
+		if _, err := ParseMessage(proto, wb); err != nil {
+			panic(err)
+		}
+		score = 1
+	}
+	return score
+}
@@ -96,7 +96,7 @@ func ParseMessage(proto int, b []byte) (*Message, error) {
 		m := &Message{Type: ipv4.ICMPType(b[0]), Code: int(b[1]), Checksum: int(b[2])<<8 | int(b[3])}
 		switch m.Type {
 		case ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply:
-			m.Body, err = parseEcho(b[4:])
+			m.Body, err = ParseEcho(b[4:])
 			if err != nil {
 				return nil, err
 			}
@@ -108,7 +108,7 @@ func ParseMessage(proto int, b []byte) (*Message, error) {
 		m := &Message{Type: ipv6.ICMPType(b[0]), Code: int(b[1]), Checksum: int(b[2])<<8 | int(b[3])}
 		switch m.Type {
 		case ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply:
-			m.Body, err = parseEcho(b[4:])
+			m.Body, err = ParseEcho(b[4:])
 			if err != nil {
 				return nil, err
 			}
@@ -87,3 +87,262 @@ func TestMarshalAndParseMessageForIPv6(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTruncatedMessage(t *testing.T) {
+	var tests []icmp.Message
+	tests = append(tests, marshalAndParseMessageForIPv4Tests...)
+	tests = append(tests, marshalAndParseMessageForIPv6Tests...)
+	for _, tt := range tests {
+		b, err := tt.Marshal(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, proto := range []int{iana.ProtocolICMP, iana.ProtocolIPv6ICMP} {
+			for i := 0; i < len(b); i++ {
+				icmp.ParseMessage(proto, b[:i]) // must not panic
+			}
+		}
+	}
+}
+
+func TestEchoReply(t *testing.T) {
+	for _, tt := range []struct {
+		req, rep icmp.Type
+	}{
+		{ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply},
+		{ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply},
+	} {
+		req := &icmp.Message{
+			Type: tt.req, Code: 0,
+			Body: &icmp.Echo{
+				ID: 1, Seq: 2,
+				Data: []byte("HELLO-R-U-THERE"),
+			},
+		}
+		rep, err := icmp.EchoReply(req)
+		if err != nil {
+			t.Fatalf("icmp.EchoReply failed: %v", err)
+		}
+		expected := &icmp.Message{Type: tt.rep, Code: 0, Body: req.Body}
+		if !reflect.DeepEqual(rep, expected) {
+			t.Fatalf("got %#v; expected %#v", rep, expected)
+		}
+		if _, err := rep.Marshal(nil); err != nil {
+			t.Fatalf("icmp.Message.Marshal failed: %v", err)
+		}
+	}
+	for _, m := range []*icmp.Message{
+		nil,
+		{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{}},
+		{Type: ipv4.ICMPTypeEcho, Body: &icmp.DefaultMessageBody{}},
+	} {
+		if _, err := icmp.EchoReply(m); err == nil {
+			t.Errorf("icmp.EchoReply(%#v) succeeded; expected error", m)
+		}
+	}
+}
+
+func TestDestinationUnreachable(t *testing.T) {
+	for _, l := range []int{ipv4.HeaderLen + 8, 548, 1500} {
+		dgram := make([]byte, l)
+		for i := range dgram {
+			dgram[i] = byte(i)
+		}
+		m := icmp.DestinationUnreachable(3, dgram)
+		b, err := m.Marshal(nil)
+		if err != nil {
+			t.Fatalf("icmp.Message.Marshal failed: %v", err)
+		}
+		if ipv4.HeaderLen+len(b) > 576 {
+			t.Fatalf("got %v bytes; expected no more than %v", ipv4.HeaderLen+len(b), 576)
+		}
+		if b[0] != byte(ipv4.ICMPTypeDestinationUnreachable) || b[1] != 3 {
+			t.Fatalf("got type=%v, code=%v; expected type=%v, code=%v", b[0], b[1], ipv4.ICMPTypeDestinationUnreachable, 3)
+		}
+		if !reflect.DeepEqual(b[4:8], []byte{0, 0, 0, 0}) {
+			t.Fatalf("got unused field %v; expected zero", b[4:8])
+		}
+		n := len(b) - 8
+		if n != l && n != 548 {
+			t.Fatalf("got %v bytes of original datagram; expected %v", n, l)
+		}
+		if !reflect.DeepEqual(b[8:], dgram[:n]) {
+			t.Fatalf("got %v; expected %v", b[8:], dgram[:n])
+		}
+	}
+}
+
+func TestTimeExceededFor(t *testing.T) {
+	dgram := []byte{
+		0x46, 0x00, 0x00, 0x2c, 0xbe, 0xef, 0x00, 0x00,
+		0x01, 0x11, 0x00, 0x00, 192, 0, 2, 1,
+		198, 51, 100, 1,
+		0x01, 0x01, 0x01, 0x00, // options
+		0x30, 0x39, 0x82, 0x9b, 0x00, 0x08, 0x00, 0x00,
+		0xde, 0xad, 0xbe, 0xef,
+	}
+	m, err := icmp.TimeExceededFor(dgram, 0)
+	if err != nil {
+		t.Fatalf("icmp.TimeExceededFor failed: %v", err)
+	}
+	if m.Type != ipv4.ICMPTypeTimeExceeded || m.Code != 0 {
+		t.Fatalf("got type=%v, code=%v; expected type=%v, code=%v", m.Type, m.Code, ipv4.ICMPTypeTimeExceeded, 0)
+	}
+	b, ok := m.OriginalDatagram()
+	if !ok || !reflect.DeepEqual(b, dgram[:24+8]) {
+		t.Fatalf("got %v; expected %v", b, dgram[:24+8])
+	}
+	if ttl, ok := m.OriginalTTL(); !ok || ttl != 1 {
+		t.Fatalf("got %v, %v; expected 1, true", ttl, ok)
+	}
+	for _, b := range [][]byte{nil, dgram[:ipv4.HeaderLen-1], dgram[:ipv4.HeaderLen], {0x60, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}} {
+		if _, err := icmp.TimeExceededFor(b, 0); err == nil {
+			t.Errorf("icmp.TimeExceededFor succeeded with %v", b)
+		}
+	}
+}
+
+func TestPortUnreachableFor(t *testing.T) {
+	h := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: 0x1c,
+		ID:       0xbeef,
+		TTL:      1,
+		Protocol: iana.ProtocolUDP,
+		Src:      net.IPv4(192, 0, 2, 1),
+		Dst:      net.IPv4(198, 51, 100, 1),
+	}
+	udp := []byte{0x30, 0x39, 0x82, 0x9b, 0x00, 0x08, 0x00, 0x00}
+	m, err := icmp.PortUnreachableFor(h, udp)
+	if err != nil {
+		t.Fatalf("icmp.PortUnreachableFor failed: %v", err)
+	}
+	if m.Type != ipv4.ICMPTypeDestinationUnreachable || m.Code != 3 {
+		t.Fatalf("got type=%v, code=%v; expected type=%v, code=%v", m.Type, m.Code, ipv4.ICMPTypeDestinationUnreachable, 3)
+	}
+	b, ok := m.OriginalDatagram()
+	if !ok {
+		t.Fatal("no original datagram")
+	}
+	dgram := []byte{
+		0x45, 0x00, 0x00, 0x1c, 0xbe, 0xef, 0x00, 0x00,
+		0x01, 0x11, 0x00, 0x00, 192, 0, 2, 1,
+		198, 51, 100, 1,
+		0x30, 0x39, 0x82, 0x9b, 0x00, 0x08, 0x00, 0x00,
+	}
+	// Fill in the header checksum computed by PortUnreachableFor.
+	dgram[10], dgram[11] = b[10], b[11]
+	if !reflect.DeepEqual(b, dgram) {
+		t.Fatalf("got %v; expected %v", b, dgram)
+	}
+	var s uint32
+	for i := 0; i < ipv4.HeaderLen; i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	for s>>16 != 0 {
+		s = s&0xffff + s>>16
+	}
+	if s != 0xffff {
+		t.Fatalf("got invalid header checksum %#04x", int(b[10])<<8|int(b[11]))
+	}
+	if _, err := icmp.PortUnreachableFor(h, udp[:7]); err == nil {
+		t.Fatal("icmp.PortUnreachableFor succeeded with short transport")
+	}
+	if _, err := icmp.PortUnreachableFor(nil, udp); err == nil {
+		t.Fatal("icmp.PortUnreachableFor succeeded without header")
+	}
+}
+
+func TestOriginalTTL(t *testing.T) {
+	ipv4Dgram := []byte{
+		0x45, 0x00, 0x00, 0x1c, 0xbe, 0xef, 0x00, 0x00,
+		0x01, 0x11, 0x00, 0x00, 192, 0, 2, 1,
+		198, 51, 100, 1,
+		0x30, 0x39, 0x82, 0x9b, 0x00, 0x08, 0x00, 0x00,
+	}
+	ipv6Dgram := make([]byte, 48)
+	ipv6Dgram[0], ipv6Dgram[6], ipv6Dgram[7] = 0x60, 17, 2
+	for _, tt := range []struct {
+		b   []byte
+		typ icmp.Type
+		ttl int
+		ok  bool
+	}{
+		{ipv4Dgram, ipv4.ICMPTypeTimeExceeded, 1, true},
+		{ipv4Dgram, ipv4.ICMPTypeDestinationUnreachable, 1, true},
+		{ipv4Dgram[:ipv4.HeaderLen-1], ipv4.ICMPTypeTimeExceeded, 0, false},
+		{ipv4Dgram, ipv4.ICMPTypeEcho, 0, false},
+		{ipv6Dgram, ipv6.ICMPTypeTimeExceeded, 2, true},
+		{ipv6Dgram, ipv6.ICMPTypePacketTooBig, 2, true},
+		{ipv6Dgram[:39], ipv6.ICMPTypeTimeExceeded, 0, false},
+		{nil, ipv4.ICMPTypeTimeExceeded, 0, false},
+	} {
+		m := &icmp.Message{Type: tt.typ, Body: &icmp.DefaultMessageBody{Data: append([]byte{0, 0, 0, 0}, tt.b...)}}
+		if b, ok := m.OriginalDatagram(); ok {
+			if string(b) != string(tt.b) {
+				t.Errorf("%v: got %v; expected %v", tt.typ, b, tt.b)
+			}
+		} else if tt.typ != ipv4.ICMPTypeEcho {
+			t.Errorf("%v: no original datagram", tt.typ)
+		}
+		ttl, ok := m.OriginalTTL()
+		if ttl != tt.ttl || ok != tt.ok {
+			t.Errorf("%v: got %v, %v; expected %v, %v", tt.typ, ttl, ok, tt.ttl, tt.ok)
+		}
+	}
+}
+
+var errorFieldTests = []struct {
+	typ   icmp.Type
+	code  int
+	field []byte
+	kind  ipv4.ICMPErrField
+	value uint32
+}{
+	{ipv4.ICMPTypeDestinationUnreachable, 3, []byte{0, 0, 0, 0}, ipv4.ICMPErrFieldUnused, 0},
+	{ipv4.ICMPTypeDestinationUnreachable, 4, []byte{0, 0, 0x05, 0xdc}, ipv4.ICMPErrFieldMTU, 1500},
+	{ipv4.ICMPTypeRedirect, 1, []byte{192, 0, 2, 1}, ipv4.ICMPErrFieldGateway, 0xc0000201},
+	{ipv4.ICMPTypeTimeExceeded, 0, []byte{0, 0, 0, 0}, ipv4.ICMPErrFieldUnused, 0},
+	{ipv4.ICMPTypeParameterProblem, 0, []byte{20, 0, 0, 0}, ipv4.ICMPErrFieldPointer, 20},
+	{ipv4.ICMPTypeParameterProblem, 1, []byte{0, 0, 0, 0}, ipv4.ICMPErrFieldUnused, 0},
+	{ipv6.ICMPTypeDestinationUnreachable, 4, []byte{0, 0, 0, 0}, ipv4.ICMPErrFieldUnused, 0},
+	{ipv6.ICMPTypePacketTooBig, 0, []byte{0, 0, 0x05, 0x00}, ipv4.ICMPErrFieldMTU, 1280},
+	{ipv6.ICMPTypeTimeExceeded, 0, []byte{0, 0, 0, 0}, ipv4.ICMPErrFieldUnused, 0},
+	{ipv6.ICMPTypeParameterProblem, 0, []byte{0, 0, 0, 6}, ipv4.ICMPErrFieldPointer, 6},
+	{ipv4.ICMPTypeEcho, 0, []byte{0, 1, 0, 1}, ipv4.ICMPErrFieldNone, 0},
+	{ipv4.ICMPTypeTimeExceeded, 0, []byte{0, 0}, ipv4.ICMPErrFieldNone, 0},
+}
+
+func TestErrorField(t *testing.T) {
+	for _, tt := range errorFieldTests {
+		m := &icmp.Message{Type: tt.typ, Code: tt.code, Body: &icmp.DefaultMessageBody{Data: tt.field}}
+		kind, value := m.ErrorField()
+		if kind != tt.kind || value != tt.value {
+			t.Errorf("%v, code %v: got %v, %v; expected %v, %v", tt.typ, tt.code, kind, value, tt.kind, tt.value)
+		}
+	}
+}
+
+func TestEchoByteOrder(t *testing.T) {
+	p := &icmp.Echo{ID: 0x1234, Seq: 0xabcd, Data: []byte{0xff}}
+	b, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("icmp.Echo.Marshal failed: %v", err)
+	}
+	// The identifier and sequence number are always in network
+	// byte order regardless of the byte order of the platform.
+	if expected := []byte{0x12, 0x34, 0xab, 0xcd, 0xff}; !reflect.DeepEqual(b, expected) {
+		t.Fatalf("got %#v; expected %#v", b, expected)
+	}
+	np, err := icmp.ParseEcho(b)
+	if err != nil {
+		t.Fatalf("icmp.ParseEcho failed: %v", err)
+	}
+	if !reflect.DeepEqual(np, p) {
+		t.Fatalf("got %#v; expected %#v", np, p)
+	}
+	if _, err := icmp.ParseEcho(b[:3]); err == nil {
+		t.Fatal("icmp.ParseEcho succeeded with truncated body")
+	}
+}
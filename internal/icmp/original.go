@@ -0,0 +1,111 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icmp
+
+import (
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	ipv6Version   = 6
+	ipv6HeaderLen = 40
+)
+
+// OriginalDatagram returns the leading part of the original datagram
+// that triggered the ICMP error message m, beginning with its IP
+// header.  It reports false when m is not an error message carrying
+// the original datagram, that is an ICMP for IPv4 destination
+// unreachable, redirect, time exceeded or parameter problem message,
+// or an ICMP for IPv6 destination unreachable, packet too big, time
+// exceeded or parameter problem message.
+func (m *Message) OriginalDatagram() ([]byte, bool) {
+	if m == nil {
+		return nil, false
+	}
+	switch m.Type {
+	case ipv4.ICMPTypeDestinationUnreachable, ipv4.ICMPTypeRedirect, ipv4.ICMPTypeTimeExceeded, ipv4.ICMPTypeParameterProblem:
+	case ipv6.ICMPTypeDestinationUnreachable, ipv6.ICMPTypePacketTooBig, ipv6.ICMPTypeTimeExceeded, ipv6.ICMPTypeParameterProblem:
+	default:
+		return nil, false
+	}
+	p, ok := m.Body.(*DefaultMessageBody)
+	if !ok || p == nil || len(p.Data) < 4 {
+		return nil, false
+	}
+	// The first 4 octets of the body are either unused or carry
+	// a type-specific parameter such as the next-hop MTU.
+	return p.Data[4:], true
+}
+
+// OriginalTTL returns the time-to-live or hop limit field value of
+// the IP header of the original datagram carried by the ICMP error
+// message m.  It reports false when m carries no original datagram
+// or the leading part is too short to hold the field.
+func (m *Message) OriginalTTL() (int, bool) {
+	b, ok := m.OriginalDatagram()
+	if !ok || len(b) == 0 {
+		return 0, false
+	}
+	switch b[0] >> 4 {
+	case ipv4.Version:
+		if len(b) < ipv4.HeaderLen {
+			return 0, false
+		}
+		return int(b[8]), true
+	case ipv6Version:
+		if len(b) < ipv6HeaderLen {
+			return 0, false
+		}
+		return int(b[7]), true
+	default:
+		return 0, false
+	}
+}
+
+// ErrorField returns the kind and the value of the 32-bit field
+// following the checksum field of the ICMP error message m, which
+// lets a caller log the error messages of any type alike.  The value
+// is:
+//
+//   - the next-hop MTU, in the low-order 16 bits of the field as
+//     specified in RFC 1191, for an ICMP for IPv4 destination
+//     unreachable message with the fragmentation needed code, or the
+//     MTU for an ICMP for IPv6 packet too big message
+//   - the gateway address in network byte order for an ICMP for IPv4
+//     redirect message
+//   - the pointer, held in the high-order 8 bits of the field, for an
+//     ICMP for IPv4 parameter problem message, except for the one
+//     reporting a missing required option, or the pointer for an ICMP
+//     for IPv6 parameter problem message
+//   - the field as is for the other error messages, for which the
+//     field is unused
+//
+// It returns ipv4.ICMPErrFieldNone when m is not an error message
+// carrying the field.
+func (m *Message) ErrorField() (kind ipv4.ICMPErrField, value uint32) {
+	if _, ok := m.OriginalDatagram(); !ok {
+		return ipv4.ICMPErrFieldNone, 0
+	}
+	b := m.Body.(*DefaultMessageBody).Data
+	v := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	switch m.Type {
+	case ipv4.ICMPTypeDestinationUnreachable:
+		if m.Code == 4 { // fragmentation needed and DF set
+			return ipv4.ICMPErrFieldMTU, v & 0xffff
+		}
+	case ipv4.ICMPTypeRedirect:
+		return ipv4.ICMPErrFieldGateway, v
+	case ipv4.ICMPTypeParameterProblem:
+		if m.Code != 1 { // other than missing a required option
+			return ipv4.ICMPErrFieldPointer, v >> 24
+		}
+	case ipv6.ICMPTypePacketTooBig:
+		return ipv4.ICMPErrFieldMTU, v
+	case ipv6.ICMPTypeParameterProblem:
+		return ipv4.ICMPErrFieldPointer, v
+	}
+	return ipv4.ICMPErrFieldUnused, v
+}
@@ -0,0 +1,157 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icmp
+
+import (
+	"errors"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// EchoReply returns the echo reply message for the echo request
+// message req, either an ICMP for IPv4 or an ICMP for IPv6 one, as
+// specified in RFC 792 and RFC 4443.  The reply carries the same
+// identifier, sequence number and data as req.
+func EchoReply(req *Message) (*Message, error) {
+	if req == nil {
+		return nil, errors.New("invalid argument")
+	}
+	var typ Type
+	switch req.Type {
+	case ipv4.ICMPTypeEcho:
+		typ = ipv4.ICMPTypeEchoReply
+	case ipv6.ICMPTypeEchoRequest:
+		typ = ipv6.ICMPTypeEchoReply
+	default:
+		return nil, errors.New("not an echo request")
+	}
+	p, ok := req.Body.(*Echo)
+	if !ok || p == nil {
+		return nil, errors.New("invalid echo request body")
+	}
+	rp := &Echo{ID: p.ID, Seq: p.Seq}
+	if len(p.Data) > 0 {
+		rp.Data = make([]byte, len(p.Data))
+		copy(rp.Data, p.Data)
+	}
+	return &Message{Type: typ, Code: 0, Body: rp}, nil
+}
+
+// maxUnreachLen is the maximum length of ICMP for IPv4 error
+// messages, including the IPv4 header, as recommended in RFC 1812.
+const maxUnreachLen = 576
+
+// DestinationUnreachable returns the ICMP for IPv4 destination
+// unreachable message with the code code for the IPv4 datagram
+// original, beginning with the IPv4 header.  The message carries as
+// much of original as possible without the IPv4 datagram carrying the
+// message exceeding 576 bytes, as specified in RFC 792 and RFC 1812,
+// which is always more than the IPv4 header and the first 64 bits of
+// the payload required by RFC 792.  The unused field is left zero;
+// the next-hop MTU for the fragmentation needed code is not filled
+// in.
+func DestinationUnreachable(code int, original []byte) *Message {
+	l := len(original)
+	if max := maxUnreachLen - ipv4.HeaderLen - 8; l > max {
+		l = max
+	}
+	b := make([]byte, 4+l)
+	copy(b[4:], original[:l])
+	return &Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: code, Body: &DefaultMessageBody{Data: b}}
+}
+
+// TimeExceededFor returns the ICMP for IPv4 time exceeded message
+// with the code code, either 0 for time to live exceeded in transit
+// or 1 for fragment reassembly time exceeded, for the IPv4 datagram
+// original, beginning with the IPv4 header.  The message carries the
+// IPv4 header of original, including the options, and the first 64
+// bits of its payload, as specified in RFC 792.  It returns an error
+// when original doesn't begin with a valid IPv4 header.
+func TimeExceededFor(original []byte, code int) (*Message, error) {
+	if len(original) < ipv4.HeaderLen || original[0]>>4 != ipv4.Version {
+		return nil, errors.New("invalid original datagram")
+	}
+	hdrlen := int(original[0]&0x0f) << 2
+	if hdrlen < ipv4.HeaderLen || hdrlen > len(original) {
+		return nil, errors.New("invalid original datagram")
+	}
+	l := len(original)
+	if max := hdrlen + 8; l > max {
+		l = max
+	}
+	b := make([]byte, 4+l)
+	copy(b[4:], original[:l])
+	return &Message{Type: ipv4.ICMPTypeTimeExceeded, Code: code, Body: &DefaultMessageBody{Data: b}}, nil
+}
+
+// PortUnreachableFor returns the ICMP for IPv4 destination
+// unreachable message with the port unreachable code for the IPv4
+// datagram consisting of the IPv4 header ipHeader and the transport
+// header and payload transport, such as a UDP datagram received on a
+// port with no listener.  The carried part of the original datagram
+// is chosen as DestinationUnreachable does.
+//
+// The transport must be at least 8 bytes long, so that the message
+// carries the first 64 bits of the payload of the original datagram,
+// which hold the ports of UDP and TCP, as required by RFC 792.  The
+// header is encoded in wire format from ipHeader, with the header
+// checksum computed when the Checksum field of ipHeader is zero.
+func PortUnreachableFor(ipHeader *ipv4.Header, transport []byte) (*Message, error) {
+	if ipHeader == nil {
+		return nil, errors.New("invalid argument")
+	}
+	if len(transport) < 8 {
+		return nil, errors.New("transport too short")
+	}
+	b, err := marshalIPv4Header(ipHeader, len(transport))
+	if err != nil {
+		return nil, err
+	}
+	return DestinationUnreachable(3, append(b, transport...)), nil
+}
+
+// marshalIPv4Header returns the IPv4 header h in wire format for the
+// datagram carrying the payload of length l.  The total length field
+// is taken from h when specified, otherwise computed from l.
+func marshalIPv4Header(h *ipv4.Header, l int) ([]byte, error) {
+	src, dst := h.Src.To4(), h.Dst.To4()
+	if src == nil || dst == nil {
+		return nil, errors.New("missing address")
+	}
+	hdrlen := ipv4.HeaderLen + len(h.Options)
+	if hdrlen > 60 || len(h.Options)&3 != 0 {
+		return nil, errors.New("invalid header options")
+	}
+	totalLen := h.TotalLen
+	if totalLen == 0 {
+		totalLen = hdrlen + l
+	}
+	flagsAndFragOff := (h.FragOff & 0x1fff) | int(h.Flags<<13)
+	b := make([]byte, hdrlen)
+	b[0] = byte(ipv4.Version<<4 | hdrlen>>2)
+	b[1] = byte(h.TOS)
+	b[2], b[3] = byte(totalLen>>8), byte(totalLen)
+	b[4], b[5] = byte(h.ID>>8), byte(h.ID)
+	b[6], b[7] = byte(flagsAndFragOff>>8), byte(flagsAndFragOff)
+	b[8] = byte(h.TTL)
+	b[9] = byte(h.Protocol)
+	copy(b[12:16], src)
+	copy(b[16:20], dst)
+	copy(b[ipv4.HeaderLen:], h.Options)
+	cs := h.Checksum
+	if cs == 0 {
+		var s uint32
+		for i := 0; i < hdrlen; i += 2 {
+			s += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		for s>>16 != 0 {
+			s = s&0xffff + s>>16
+		}
+		cs = int(^uint16(s))
+	}
+	b[10], b[11] = byte(cs>>8), byte(cs)
+	return b, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// References:
+//
+// RFC 1112  Host Extensions for IP Multicasting
+//	http://tools.ietf.org/html/rfc1112
+// RFC 1918  Address Allocation for Private Internets
+//	http://tools.ietf.org/html/rfc1918
+// RFC 3927  Dynamic Configuration of IPv4 Link-Local Addresses
+//	http://tools.ietf.org/html/rfc3927
+// RFC 5737  IPv4 Address Blocks Reserved for Documentation
+//	http://tools.ietf.org/html/rfc5737
+// RFC 6598  IANA-Reserved IPv4 Prefix for Shared Address Space
+//	http://tools.ietf.org/html/rfc6598
+// RFC 6890  Special-Purpose IP Address Registries
+//	http://tools.ietf.org/html/rfc6890
+
+// An AddrClass represents a set of classes of an IPv4 address.
+type AddrClass uint
+
+const (
+	AddrUnspecified   AddrClass = 1 << iota // "this network", 0.0.0.0/8
+	AddrLoopback                            // loopback, 127.0.0.0/8
+	AddrPrivate                             // private-use, 10.0.0.0/8, 172.16.0.0/12 and 192.168.0.0/16
+	AddrLinkLocal                           // link-local unicast, 169.254.0.0/16
+	AddrMulticast                           // multicast, 224.0.0.0/4
+	AddrBroadcast                           // limited broadcast, 255.255.255.255
+	AddrDocumentation                       // documentation, 192.0.2.0/24, 198.51.100.0/24 and 203.0.113.0/24
+	AddrCGNAT                               // shared address space for carrier-grade NAT, 100.64.0.0/10
+	AddrReserved                            // reserved for future use, 240.0.0.0/4 except the limited broadcast
+	AddrGlobal                              // global unicast, any other address
+)
+
+var addrClassNames = []struct {
+	c    AddrClass
+	name string
+}{
+	{AddrUnspecified, "AddrUnspecified"},
+	{AddrLoopback, "AddrLoopback"},
+	{AddrPrivate, "AddrPrivate"},
+	{AddrLinkLocal, "AddrLinkLocal"},
+	{AddrMulticast, "AddrMulticast"},
+	{AddrBroadcast, "AddrBroadcast"},
+	{AddrDocumentation, "AddrDocumentation"},
+	{AddrCGNAT, "AddrCGNAT"},
+	{AddrReserved, "AddrReserved"},
+	{AddrGlobal, "AddrGlobal"},
+}
+
+// String returns the textual representation of ac in the form of
+// class names joined by "|", such as "AddrMulticast", and no classes
+// as "0".
+func (ac AddrClass) String() string {
+	if ac == 0 {
+		return "0"
+	}
+	var names []string
+	for _, cn := range addrClassNames {
+		if ac&cn.c != 0 {
+			names = append(names, cn.name)
+			ac &^= cn.c
+		}
+	}
+	if ac != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint(ac)))
+	}
+	return strings.Join(names, "|")
+}
+
+// addrClassTable lists the special-purpose address blocks.  The
+// limited broadcast precedes the reserved block covering it.
+var addrClassTable = []struct {
+	c      AddrClass
+	prefix [4]byte
+	bits   uint
+}{
+	{AddrUnspecified, [4]byte{0, 0, 0, 0}, 8},
+	{AddrLoopback, [4]byte{127, 0, 0, 0}, 8},
+	{AddrPrivate, [4]byte{10, 0, 0, 0}, 8},
+	{AddrPrivate, [4]byte{172, 16, 0, 0}, 12},
+	{AddrPrivate, [4]byte{192, 168, 0, 0}, 16},
+	{AddrLinkLocal, [4]byte{169, 254, 0, 0}, 16},
+	{AddrMulticast, [4]byte{224, 0, 0, 0}, 4},
+	{AddrBroadcast, [4]byte{255, 255, 255, 255}, 32},
+	{AddrDocumentation, [4]byte{192, 0, 2, 0}, 24},
+	{AddrDocumentation, [4]byte{198, 51, 100, 0}, 24},
+	{AddrDocumentation, [4]byte{203, 0, 113, 0}, 24},
+	{AddrCGNAT, [4]byte{100, 64, 0, 0}, 10},
+	{AddrReserved, [4]byte{240, 0, 0, 0}, 4},
+}
+
+// Classify returns the class of the IPv4 address ip.  Exactly one
+// class is returned for an IPv4 address; AddrGlobal is returned when
+// ip belongs to none of the special-purpose address blocks above.
+// It returns zero when ip is not an IPv4 address or an IPv4-mapped
+// IPv6 address.
+//
+// As AddrClass is a bit mask, a policy can be expressed as a set of
+// classes, such as:
+//
+//	if ipv4.Classify(ip)&(ipv4.AddrLoopback|ipv4.AddrPrivate) != 0 {
+//		// ip is internal
+//	}
+func Classify(ip net.IP) AddrClass {
+	ip = ip.To4()
+	if ip == nil {
+		return 0
+	}
+	a := uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+	for _, e := range addrClassTable {
+		p := uint32(e.prefix[0])<<24 | uint32(e.prefix[1])<<16 | uint32(e.prefix[2])<<8 | uint32(e.prefix[3])
+		mask := ^uint32(0) << (32 - e.bits)
+		if a&mask == p {
+			return e.c
+		}
+	}
+	return AddrGlobal
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+var classifyTests = []struct {
+	ip net.IP
+	ac ipv4.AddrClass
+}{
+	{net.IPv4(0, 0, 0, 0), ipv4.AddrUnspecified},
+	{net.IPv4(0, 255, 255, 255), ipv4.AddrUnspecified},
+	{net.IPv4(1, 0, 0, 0), ipv4.AddrGlobal},
+
+	{net.IPv4(127, 0, 0, 1), ipv4.AddrLoopback},
+	{net.IPv4(127, 255, 255, 254), ipv4.AddrLoopback},
+	{net.IPv4(126, 255, 255, 255), ipv4.AddrGlobal},
+	{net.IPv4(128, 0, 0, 0), ipv4.AddrGlobal},
+
+	{net.IPv4(10, 0, 0, 1), ipv4.AddrPrivate},
+	{net.IPv4(10, 255, 255, 255), ipv4.AddrPrivate},
+	{net.IPv4(11, 0, 0, 0), ipv4.AddrGlobal},
+	{net.IPv4(172, 16, 0, 0), ipv4.AddrPrivate},
+	{net.IPv4(172, 31, 255, 255), ipv4.AddrPrivate},
+	{net.IPv4(172, 15, 255, 255), ipv4.AddrGlobal},
+	{net.IPv4(172, 32, 0, 0), ipv4.AddrGlobal},
+	{net.IPv4(192, 168, 0, 1), ipv4.AddrPrivate},
+	{net.IPv4(192, 168, 255, 255), ipv4.AddrPrivate},
+	{net.IPv4(192, 169, 0, 0), ipv4.AddrGlobal},
+
+	{net.IPv4(169, 254, 0, 1), ipv4.AddrLinkLocal},
+	{net.IPv4(169, 254, 255, 255), ipv4.AddrLinkLocal},
+	{net.IPv4(169, 253, 255, 255), ipv4.AddrGlobal},
+
+	{net.IPv4(224, 0, 0, 1), ipv4.AddrMulticast},
+	{net.IPv4(239, 255, 255, 255), ipv4.AddrMulticast},
+	{net.IPv4(223, 255, 255, 255), ipv4.AddrGlobal},
+
+	{net.IPv4(255, 255, 255, 255), ipv4.AddrBroadcast},
+	{net.IPv4(240, 0, 0, 0), ipv4.AddrReserved},
+	{net.IPv4(255, 255, 255, 254), ipv4.AddrReserved},
+
+	{net.IPv4(192, 0, 2, 1), ipv4.AddrDocumentation},
+	{net.IPv4(198, 51, 100, 255), ipv4.AddrDocumentation},
+	{net.IPv4(203, 0, 113, 0), ipv4.AddrDocumentation},
+	{net.IPv4(192, 0, 3, 0), ipv4.AddrGlobal},
+	{net.IPv4(198, 51, 101, 0), ipv4.AddrGlobal},
+	{net.IPv4(203, 0, 112, 255), ipv4.AddrGlobal},
+
+	{net.IPv4(100, 64, 0, 0), ipv4.AddrCGNAT},
+	{net.IPv4(100, 127, 255, 255), ipv4.AddrCGNAT},
+	{net.IPv4(100, 63, 255, 255), ipv4.AddrGlobal},
+	{net.IPv4(100, 128, 0, 0), ipv4.AddrGlobal},
+
+	{net.IPv4(8, 8, 8, 8), ipv4.AddrGlobal},
+	{net.IPv4(8, 8, 8, 8).To4(), ipv4.AddrGlobal},
+	{net.ParseIP("::ffff:10.0.0.1"), ipv4.AddrPrivate},
+
+	{net.ParseIP("2001:db8::1"), 0},
+	{net.IPv6loopback, 0},
+	{nil, 0},
+}
+
+func TestClassify(t *testing.T) {
+	for _, tt := range classifyTests {
+		if ac := ipv4.Classify(tt.ip); ac != tt.ac {
+			t.Errorf("%v: got %v; expected %v", tt.ip, ac, tt.ac)
+		}
+	}
+}
+
+func TestAddrClassString(t *testing.T) {
+	for _, tt := range []struct {
+		ac ipv4.AddrClass
+		s  string
+	}{
+		{0, "0"},
+		{ipv4.AddrCGNAT, "AddrCGNAT"},
+		{ipv4.AddrLoopback | ipv4.AddrPrivate, "AddrLoopback|AddrPrivate"},
+		{ipv4.AddrGlobal | 1<<20, "AddrGlobal|0x100000"},
+	} {
+		if s := tt.ac.String(); s != tt.s {
+			t.Errorf("got %q; expected %q", s, tt.s)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"syscall"
+)
+
+// A Message represents an IPv4 datagram payload to be written by
+// WriteBatchResults.
+type Message struct {
+	Buffers [][]byte        // payload, written as a single datagram
+	CM      *ControlMessage // control message, may be nil
+	Addr    net.Addr        // destination address
+	N       int             // number of bytes written, filled in on return
+}
+
+// WriteBatchResults writes the payloads of the messages ms, each as a
+// separate datagram, through the endpoint c in order, and reports the
+// result of each write.  Unlike a single failed WriteTo, a failure on
+// a message doesn't stop the batch; the returned slice holds the
+// error for each message at the same index, nil for the messages
+// written successfully, whose N field holds the number of bytes
+// written.  The second return value is non-nil only when the batch
+// as a whole cannot be written, in which case no message is written.
+//
+// It is useful for an application sending a burst of datagrams to
+// many destinations, such as a network scanner, to learn which
+// destinations are unreachable.  The flags is reserved for future
+// use and must be zero.
+func (c *PacketConn) WriteBatchResults(ms []Message, flags int) ([]error, error) {
+	if !c.payloadHandler.ok() || flags != 0 {
+		return nil, syscall.EINVAL
+	}
+	errs := make([]error, len(ms))
+	var b []byte
+	for i := range ms {
+		m := &ms[i]
+		var p []byte
+		if len(m.Buffers) == 1 {
+			p = m.Buffers[0]
+		} else {
+			b = b[:0]
+			for _, bb := range m.Buffers {
+				b = append(b, bb...)
+			}
+			p = b
+		}
+		m.N, errs[i] = c.WriteTo(p, m.CM, m.Addr)
+	}
+	return errs, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+func marshalFragSize(b []byte, cm *ControlMessage) []byte {
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolIP
+	m.Type = sysIP_RECVFRAGSIZE
+	m.SetLen(syscall.CmsgLen(4))
+	return b[syscall.CmsgSpace(4):]
+}
+
+func parseFragSize(cm *ControlMessage, b []byte) {
+	cm.FragSize = int(*(*int32)(unsafe.Pointer(&b[:4][0])))
+}
+
+// appendMulticastTTL appends the IP_TTL control message carrying ttl
+// to oob.  The protocol stack uses the value for the outgoing packet
+// in place of the socket-wide time-to-live, which is IP_MULTICAST_TTL
+// for multicast destinations.
+func appendMulticastTTL(oob []byte, ttl int) []byte {
+	b := make([]byte, syscall.CmsgSpace(4))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolIP
+	m.Type = sysIP_TTL
+	m.SetLen(syscall.CmsgLen(4))
+	*(*int32)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = int32(ttl)
+	return append(oob, b...)
+}
@@ -0,0 +1,34 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+func TestAppendMulticastTTL(t *testing.T) {
+	oob := appendMulticastTTL(nil, 1)
+	cmsgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		t.Fatalf("syscall.ParseSocketControlMessage failed: %v", err)
+	}
+	if len(cmsgs) != 1 {
+		t.Fatalf("got %v control messages; expected 1", len(cmsgs))
+	}
+	m := cmsgs[0]
+	if m.Header.Level != iana.ProtocolIP || m.Header.Type != sysIP_TTL {
+		t.Fatalf("got level=%v, type=%v; expected level=%v, type=%v", m.Header.Level, m.Header.Type, iana.ProtocolIP, sysIP_TTL)
+	}
+	if len(m.Data) < 4 {
+		t.Fatalf("got %v bytes of data; expected 4", len(m.Data))
+	}
+	if v := *(*int32)(unsafe.Pointer(&m.Data[0])); v != 1 {
+		t.Fatalf("got %v; expected 1", v)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd windows
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// Connect connects the endpoint to the peer ip, which allows Write to
+// send datagrams without specifying the destination address and
+// makes the protocol stack deliver only the datagrams from the peer
+// to the endpoint.  It also lets the protocol stack report the
+// asynchronous errors caused by the datagrams sent to the peer, such
+// as ICMP destination unreachable messages, to later reads and
+// writes.
+//
+// Since the endpoint always has the IP_HDRINCL socket option enabled,
+// the protocol stack doesn't rewrite the destination address field of
+// the IPv4 header passed to Write and WriteTo; it only uses the peer
+// for routing when no address is given.  Write fills in the field
+// with the peer when it is left unspecified.
+func (c *RawConn) Connect(ip net.IP) error {
+	if !c.packetHandler.ok() {
+		return syscall.EINVAL
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return errNonIPv4Address
+	}
+	fd, err := c.packetHandler.sysfd()
+	if err != nil {
+		return err
+	}
+	sa := &syscall.SockaddrInet4{}
+	copy(sa.Addr[:], ip4)
+	if err := syscall.Connect(fd, sa); err != nil {
+		return os.NewSyscallError("connect", err)
+	}
+	c.packetHandler.rawOpt.Lock()
+	c.packetHandler.peer = ip4
+	c.packetHandler.rawOpt.Unlock()
+	return nil
+}
@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build nacl plan9 solaris
+
+package ipv4
+
+import "net"
+
+// Connect connects the endpoint to the peer ip.
+// It is not supported on this platform.
+func (c *RawConn) Connect(ip net.IP) error {
+	return ErrNotSupported
+}
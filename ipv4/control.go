@@ -5,14 +5,23 @@
 package ipv4
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type rawOpt struct {
 	sync.RWMutex
-	cflags ControlFlags
+	cflags  ControlFlags
+	ifnames interfaceNames
+	txtime  bool // transmit time is passed to the protocol stack
+	gro     bool // generic receive offload is enabled
+	gsoSize int  // segment size for generic segmentation offload
 }
 
 func (c *rawOpt) set(f ControlFlags)        { c.cflags |= f }
@@ -22,13 +31,101 @@ func (c *rawOpt) isset(f ControlFlags) bool { return c.cflags&f != 0 }
 type ControlFlags uint
 
 const (
-	FlagTTL       ControlFlags = 1 << iota // pass the TTL on the received packet
-	FlagSrc                                // pass the source address on the received packet
-	FlagDst                                // pass the destination address on the received packet
-	FlagInterface                          // pass the interface index on the received packet
+	FlagTTL         ControlFlags = 1 << iota // pass the TTL on the received packet
+	FlagSrc                                  // pass the source address on the received packet
+	FlagDst                                  // pass the destination address on the received packet
+	FlagInterface                            // pass the interface index on the received packet
+	FlagFragSize                             // pass the largest fragment size of the reassembled packet
+	FlagTOS                                  // pass the type-of-service field on the received packet
+	FlagRawHeader                            // pass the IPv4 header of the received packet, PacketConn on IP endpoints only
+	FlagIncomingCPU                          // pass the CPU on which the received packet was processed
 )
 
+var controlFlagNames = []struct {
+	f    ControlFlags
+	name string
+}{
+	{FlagTTL, "FlagTTL"},
+	{FlagSrc, "FlagSrc"},
+	{FlagDst, "FlagDst"},
+	{FlagInterface, "FlagInterface"},
+	{FlagFragSize, "FlagFragSize"},
+	{FlagTOS, "FlagTOS"},
+	{FlagRawHeader, "FlagRawHeader"},
+	{FlagIncomingCPU, "FlagIncomingCPU"},
+}
+
+// String returns the textual representation of cf in the form of
+// flag names joined by "|", such as "FlagTTL|FlagDst|FlagInterface".
+// Unknown bits are rendered as a hexadecimal number at the end, and
+// no flags as "0".
+func (cf ControlFlags) String() string {
+	if cf == 0 {
+		return "0"
+	}
+	var names []string
+	for _, fn := range controlFlagNames {
+		if cf&fn.f != 0 {
+			names = append(names, fn.name)
+			cf &^= fn.f
+		}
+	}
+	if cf != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint(cf)))
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseControlFlags parses s as the textual representation of
+// control flags returned by ControlFlags.String.  Flag names may be
+// surrounded by spaces, and an empty string represents no flags.
+func ParseControlFlags(s string) (ControlFlags, error) {
+	var cf ControlFlags
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	for _, name := range strings.Split(s, "|") {
+		name = strings.TrimSpace(name)
+		f, ok := parseControlFlag(name)
+		if !ok {
+			return 0, errors.New("invalid control flag " + strconv.Quote(name))
+		}
+		cf |= f
+	}
+	return cf, nil
+}
+
+func parseControlFlag(name string) (ControlFlags, bool) {
+	for _, fn := range controlFlagNames {
+		if name == fn.name {
+			return fn.f, true
+		}
+	}
+	v, err := strconv.ParseUint(name, 0, 0)
+	if err != nil {
+		return 0, false
+	}
+	return ControlFlags(v), true
+}
+
+// ControlMessageSpace returns the size of the buffer required to
+// receive the ancillary data for the per packet IP-level socket
+// options specified by cf on the running platform, that is the sum
+// of the space for each control message the protocol stack may pass.
+// Options that are not supported or don't need ancillary data, such
+// as FlagRawHeader, take no space.
+func ControlMessageSpace(cf ControlFlags) int {
+	return controlMessageSpace(cf)
+}
+
 // A ControlMessage represents per packet basis IP-level socket options.
+//
+// The checksum offload status of received packets is not available
+// through ControlMessage, since neither Linux nor the BSDs pass it to
+// IP or UDP sockets; Linux passes it only to packet sockets through
+// PACKET_AUXDATA.  An application that cares about the integrity of
+// the transport payload beyond what the protocol stack checks must
+// verify the checksum itself.
 type ControlMessage struct {
 	// Receiving socket options: SetControlMessage allows to
 	// receive the options from the protocol stack using ReadFrom
@@ -38,17 +135,263 @@ type ControlMessage struct {
 	// method of PacketConn or RawConn allows to send the options
 	// to the protocol stack.
 	//
-	TTL     int    // time-to-live, receiving only
-	Src     net.IP // source address, specifying only
-	Dst     net.IP // destination address, receiving only
-	IfIndex int    // interface index, must be 1 <= value when specifying
+	TTL      int     // time-to-live, receiving only
+	Src      net.IP  // source address, specifying only
+	Dst      net.IP  // destination address, receiving only
+	IfIndex  int     // interface index, must be 1 <= value when specifying
+	FragSize int     // largest fragment size of reassembled packet, receiving only
+	TOS      int     // type-of-service, receiving only
+	Header   *Header // IPv4 header, receiving only
+
+	// IncomingCPU is the CPU on which the protocol stack processed
+	// the most recent packet for the endpoint, receiving only.  It
+	// is filled in only when FlagIncomingCPU is set, and is -1 when
+	// the CPU cannot be determined.
+	IncomingCPU int
+
+	// MulticastTTL is the time-to-live for the outgoing packet
+	// to a multicast address, specifying only.  It overrides the
+	// value set by SetMulticastTTL for the packet and is ignored
+	// when zero or when the destination is a unicast address,
+	// which keeps using the value set by SetTTL.
+	// Currently only Linux supports this.
+	MulticastTTL int
+
+	// Truncated reports whether the protocol stack discarded a
+	// part of the ancillary data because the buffer for it was
+	// too small, receiving only.  Some fields may be missing then.
+	Truncated bool
+
+	// TxTime is the time at which the protocol stack transmits
+	// the outgoing packet, specifying only.  It is honored only
+	// when SetTxTime is turned on, and is ignored when zero.
+	TxTime time.Time
+
+	// GSOSize is the size of each segment of the coalesced UDP
+	// datagrams, receiving only.  It is filled in only when
+	// SetGRO is turned on and the protocol stack coalesced the
+	// received datagrams, each of which but the last has the
+	// size; see SplitGRO.
+	// Currently only Linux supports this.
+	GSOSize int
+
+	// Mark is the mark, also known as fwmark, on sending only.
+	// It overrides the value set by SetMark for the outgoing
+	// packet and is ignored when zero.
+	// Currently only Linux 6.0 or above supports this, and
+	// requires the CAP_NET_ADMIN capability.
+	Mark uint32
+
+	ifname  string          // cached interface name
+	ifnames *interfaceNames // per endpoint interface name cache
 }
 
+// String returns the textual representation of cm in the form of
+// space-separated name=value pairs, such as:
+//
+//	TTL=64 Src=192.0.2.1 Dst=224.0.0.1 IfIndex=3 FragSize=1500 TOS=0xb8 IncomingCPU=2 MulticastTTL=8 Truncated=true GSOSize=1200 Mark=0x2a
+//
+// The pairs appear in the order in which the fields are declared,
+// regardless of the platform, and cover both the receiving and the
+// specifying fields.  Fields holding zero values are omitted;
+// IncomingCPU is omitted for CPU 0 as well.  Header is quoted as it
+// contains spaces, and TxTime is formatted as in RFC 3339.  It
+// returns "<nil>" when cm is nil.
 func (cm *ControlMessage) String() string {
 	if cm == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("ttl: %v, src: %v, dst: %v, ifindex: %v", cm.TTL, cm.Src, cm.Dst, cm.IfIndex)
+	var b bytes.Buffer
+	if cm.TTL != 0 {
+		fmt.Fprintf(&b, " TTL=%d", cm.TTL)
+	}
+	if len(cm.Src) != 0 {
+		fmt.Fprintf(&b, " Src=%v", cm.Src)
+	}
+	if len(cm.Dst) != 0 {
+		fmt.Fprintf(&b, " Dst=%v", cm.Dst)
+	}
+	if cm.IfIndex != 0 {
+		fmt.Fprintf(&b, " IfIndex=%d", cm.IfIndex)
+	}
+	if cm.FragSize != 0 {
+		fmt.Fprintf(&b, " FragSize=%d", cm.FragSize)
+	}
+	if cm.TOS != 0 {
+		fmt.Fprintf(&b, " TOS=%#02x", cm.TOS)
+	}
+	if cm.Header != nil {
+		fmt.Fprintf(&b, " Header=%q", cm.Header.String())
+	}
+	if cm.IncomingCPU != 0 {
+		fmt.Fprintf(&b, " IncomingCPU=%d", cm.IncomingCPU)
+	}
+	if cm.MulticastTTL != 0 {
+		fmt.Fprintf(&b, " MulticastTTL=%d", cm.MulticastTTL)
+	}
+	if cm.Truncated {
+		b.WriteString(" Truncated=true")
+	}
+	if !cm.TxTime.IsZero() {
+		fmt.Fprintf(&b, " TxTime=%s", cm.TxTime.Format(time.RFC3339Nano))
+	}
+	if cm.GSOSize != 0 {
+		fmt.Fprintf(&b, " GSOSize=%d", cm.GSOSize)
+	}
+	if cm.Mark != 0 {
+		fmt.Fprintf(&b, " Mark=%#x", cm.Mark)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String()[1:]
+}
+
+// Fields returns the populated fields of cm keyed by the field
+// names used by String, such as "TTL" and "Src".  It covers the same
+// fields as String, holding their values as they are.  Fields holding
+// zero values are omitted.  It returns nil when cm is nil or no field
+// is populated.
+func (cm *ControlMessage) Fields() map[string]interface{} {
+	if cm == nil {
+		return nil
+	}
+	var m map[string]interface{}
+	add := func(k string, v interface{}) {
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		m[k] = v
+	}
+	if cm.TTL != 0 {
+		add("TTL", cm.TTL)
+	}
+	if len(cm.Src) != 0 {
+		add("Src", cm.Src)
+	}
+	if len(cm.Dst) != 0 {
+		add("Dst", cm.Dst)
+	}
+	if cm.IfIndex != 0 {
+		add("IfIndex", cm.IfIndex)
+	}
+	if cm.FragSize != 0 {
+		add("FragSize", cm.FragSize)
+	}
+	if cm.TOS != 0 {
+		add("TOS", cm.TOS)
+	}
+	if cm.Header != nil {
+		add("Header", cm.Header)
+	}
+	if cm.IncomingCPU != 0 {
+		add("IncomingCPU", cm.IncomingCPU)
+	}
+	if cm.MulticastTTL != 0 {
+		add("MulticastTTL", cm.MulticastTTL)
+	}
+	if cm.Truncated {
+		add("Truncated", cm.Truncated)
+	}
+	if !cm.TxTime.IsZero() {
+		add("TxTime", cm.TxTime)
+	}
+	if cm.GSOSize != 0 {
+		add("GSOSize", cm.GSOSize)
+	}
+	if cm.Mark != 0 {
+		add("Mark", cm.Mark)
+	}
+	return m
+}
+
+// ReceivedECN returns the Explicit Congestion Notification codepoint
+// carried in the two least significant bits of TOS: 0 for Not-ECT, 1
+// for ECT(1), 2 for ECT(0) and 3 for CE (Congestion Experienced).
+// TOS is filled in only when FlagTOS is set by SetControlMessage,
+// which requires the IP_RECVTOS socket option and is currently
+// supported only on FreeBSD and Linux.
+func (cm *ControlMessage) ReceivedECN() int {
+	if cm == nil {
+		return 0
+	}
+	return cm.TOS & 0x03
+}
+
+// Reply returns a control message for WriteTo that sends a response
+// to the packet received with cm out of the interface on which the
+// packet arrived, using the destination address of the packet as the
+// source address.  The source address is left unspecified when the
+// destination address is a multicast or limited broadcast address,
+// which is not valid as a source address, so that the protocol stack
+// selects one on the interface.  It returns nil when cm is nil.
+//
+// The received control message carries the destination address and
+// the interface index only when FlagDst and FlagInterface are set by
+// SetControlMessage.  Specifying the source address and the outgoing
+// interface is currently supported only on Linux.
+func (cm *ControlMessage) Reply() *ControlMessage {
+	if cm == nil {
+		return nil
+	}
+	rcm := &ControlMessage{IfIndex: cm.IfIndex}
+	if ip := cm.Dst.To4(); ip != nil && !ip.IsMulticast() && !ip.Equal(net.IPv4bcast) {
+		rcm.Src = ip
+	}
+	return rcm
+}
+
+// InterfaceName returns the name of the network interface
+// identified by IfIndex.  It returns an empty string when IfIndex is
+// not set or the interface cannot be found.
+//
+// The name is cached in cm.  When cm is returned from ReadFrom method
+// of PacketConn or RawConn, the name is also cached in the endpoint
+// and shared by subsequent control messages, so that resolving the
+// name of the same interface repeatedly doesn't cost a lookup per
+// packet.  Note that the endpoint cache isn't invalidated when the
+// interface is renamed.
+func (cm *ControlMessage) InterfaceName() string {
+	if cm == nil || cm.IfIndex < 1 {
+		return ""
+	}
+	if cm.ifname != "" {
+		return cm.ifname
+	}
+	if cm.ifnames != nil {
+		cm.ifname = cm.ifnames.lookup(cm.IfIndex)
+		return cm.ifname
+	}
+	if ifi, err := net.InterfaceByIndex(cm.IfIndex); err == nil {
+		cm.ifname = ifi.Name
+	}
+	return cm.ifname
+}
+
+// An interfaceNames represents an interface index to name cache.
+type interfaceNames struct {
+	sync.RWMutex
+	m map[int]string
+}
+
+func (c *interfaceNames) lookup(index int) string {
+	c.RLock()
+	name, ok := c.m[index]
+	c.RUnlock()
+	if ok {
+		return name
+	}
+	ifi, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return ""
+	}
+	c.Lock()
+	if c.m == nil {
+		c.m = make(map[int]string)
+	}
+	c.m[index] = ifi.Name
+	c.Unlock()
+	return ifi.Name
 }
 
 // Ancillary data socket options
@@ -58,6 +401,8 @@ const (
 	ctlDst               // header field
 	ctlInterface         // inbound or outbound interface
 	ctlPacketInfo        // inbound or outbound packet path
+	ctlFragSize          // largest fragment size of reassembled packet
+	ctlTOS               // header field
 	ctlMax
 )
 
@@ -14,6 +14,20 @@ import (
 	"golang.org/x/net/internal/iana"
 )
 
+// marshalSrc marshals the IP_SENDSRCADDR control message, which
+// shares the value of IP_RECVDSTADDR, carrying the source address of
+// the outgoing packet.
+func marshalSrc(b []byte, cm *ControlMessage) []byte {
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolIP
+	m.Type = sysIP_RECVDSTADDR
+	m.SetLen(syscall.CmsgLen(net.IPv4len))
+	if cm != nil {
+		copy(b[syscall.CmsgLen(0):], cm.Src.To4())
+	}
+	return b[syscall.CmsgSpace(net.IPv4len):]
+}
+
 func marshalDst(b []byte, cm *ControlMessage) []byte {
 	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
 	m.Level = iana.ProtocolIP
@@ -7,7 +7,19 @@
 package ipv4
 
 func setControlMessage(fd int, opt *rawOpt, cf ControlFlags, on bool) error {
-	return errOpNoSupport
+	return ErrNotSupported
+}
+
+func supportedControlFlags() ControlFlags {
+	return 0
+}
+
+func controlMessageSpace(cf ControlFlags) int {
+	return 0
+}
+
+func controlMessageTruncated(flags int) bool {
+	return false
 }
 
 func newControlMessage(opt *rawOpt) []byte {
@@ -15,7 +27,7 @@ func newControlMessage(opt *rawOpt) []byte {
 }
 
 func parseControlMessage(b []byte) (*ControlMessage, error) {
-	return nil, errOpNoSupport
+	return nil, ErrNotSupported
 }
 
 func marshalControlMessage(cm *ControlMessage) []byte {
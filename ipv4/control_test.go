@@ -0,0 +1,423 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"net"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/internal/nettest"
+	"golang.org/x/net/ipv4"
+)
+
+func TestControlMessageInterfaceName(t *testing.T) {
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	var cm *ipv4.ControlMessage
+	if name := cm.InterfaceName(); name != "" {
+		t.Fatalf("got %q; expected empty", name)
+	}
+	cm = &ipv4.ControlMessage{}
+	if name := cm.InterfaceName(); name != "" {
+		t.Fatalf("got %q; expected empty", name)
+	}
+	cm.IfIndex = ifi.Index
+	for i := 0; i < 2; i++ {
+		if name := cm.InterfaceName(); name != ifi.Name {
+			t.Fatalf("got %q; expected %q", name, ifi.Name)
+		}
+	}
+}
+
+var receivedECNTests = []struct {
+	tos int
+	ecn int
+}{
+	{iana.DiffServCS0 | iana.NotECNTransport, iana.NotECNTransport},
+	{iana.DiffServAF11 | iana.ECNTransport1, iana.ECNTransport1},
+	{iana.DiffServAF11 | iana.ECNTransport0, iana.ECNTransport0},
+	{iana.DiffServCS7 | iana.CongestionExperienced, iana.CongestionExperienced},
+}
+
+func TestControlMessageReceivedECN(t *testing.T) {
+	var cm *ipv4.ControlMessage
+	if ecn := cm.ReceivedECN(); ecn != 0 {
+		t.Fatalf("got %v; expected 0", ecn)
+	}
+	for _, tt := range receivedECNTests {
+		cm := &ipv4.ControlMessage{TOS: tt.tos}
+		if ecn := cm.ReceivedECN(); ecn != tt.ecn {
+			t.Errorf("got %v for tos %#x; expected %v", ecn, tt.tos, tt.ecn)
+		}
+	}
+}
+
+var controlMessageStringTests = []struct {
+	cm  *ipv4.ControlMessage
+	out string
+}{
+	{nil, "<nil>"},
+	{&ipv4.ControlMessage{}, ""},
+	{
+		&ipv4.ControlMessage{TTL: 64, Src: net.IPv4(10, 0, 0, 1), Dst: net.IPv4(224, 0, 0, 1), IfIndex: 3, FragSize: 1500, TOS: 0xb8},
+		"TTL=64 Src=10.0.0.1 Dst=224.0.0.1 IfIndex=3 FragSize=1500 TOS=0xb8",
+	},
+	{&ipv4.ControlMessage{Dst: net.IP{192, 0, 2, 1}, TOS: 0x1}, "Dst=192.0.2.1 TOS=0x01"},
+	{&ipv4.ControlMessage{IncomingCPU: -1, Truncated: true}, "IncomingCPU=-1 Truncated=true"},
+	{&ipv4.ControlMessage{TTL: 64, IncomingCPU: 2, GSOSize: 1200}, "TTL=64 IncomingCPU=2 GSOSize=1200"},
+	{
+		&ipv4.ControlMessage{Src: net.IPv4(192, 0, 2, 1), MulticastTTL: 8, TxTime: time.Date(2015, 1, 2, 3, 4, 5, 6, time.UTC), Mark: 0x2a},
+		"Src=192.0.2.1 MulticastTTL=8 TxTime=2015-01-02T03:04:05.000000006Z Mark=0x2a",
+	},
+	{
+		&ipv4.ControlMessage{TTL: 1, Header: &ipv4.Header{Version: ipv4.Version, Len: ipv4.HeaderLen}},
+		`TTL=1 Header="ver: 4, hdrlen: 20, tos: 0x0, totallen: 0, id: 0x0, flags: 0x0, fragoff: 0x0, ttl: 0, proto: 0, cksum: 0x0, src: <nil>, dst: <nil>"`,
+	},
+}
+
+func TestControlMessageString(t *testing.T) {
+	for _, tt := range controlMessageStringTests {
+		if s := tt.cm.String(); s != tt.out {
+			t.Errorf("got %q; expected %q", s, tt.out)
+		}
+	}
+}
+
+func TestPacketConnRawHeaderOnUDP(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if err := p.SetControlMessage(ipv4.FlagRawHeader, true); err != ipv4.ErrNotSupported {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+	}
+}
+
+func TestPacketConnReceivedTOS(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if p.SupportedControlFlags()&ipv4.FlagTOS == 0 {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	// DSCP AF11 with ECT(1).
+	if err := p.SetTOS(0xb9); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetTOS failed: %v", err)
+	}
+	if err := p.SetControlMessage(ipv4.FlagTOS, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	_, cm, _, err := p.ReadFrom(make([]byte, 128))
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if cm == nil {
+		t.Fatal("got no control message")
+	}
+	if cm.TOS != 0xb9 {
+		t.Fatalf("got %#x; expected %#x", cm.TOS, 0xb9)
+	}
+	if ecn := cm.ReceivedECN(); ecn != 1 {
+		t.Fatalf("got %v; expected 1", ecn)
+	}
+}
+
+func TestControlMessageFields(t *testing.T) {
+	var cm *ipv4.ControlMessage
+	if m := cm.Fields(); m != nil {
+		t.Fatalf("got %v; expected nil", m)
+	}
+	cm = &ipv4.ControlMessage{}
+	if m := cm.Fields(); m != nil {
+		t.Fatalf("got %v; expected nil", m)
+	}
+	cm = &ipv4.ControlMessage{TTL: 64, Dst: net.IPv4(224, 0, 0, 1), TOS: 0xb8}
+	expected := map[string]interface{}{"TTL": 64, "Dst": net.IPv4(224, 0, 0, 1), "TOS": 0xb8}
+	if m := cm.Fields(); !reflect.DeepEqual(m, expected) {
+		t.Fatalf("got %v; expected %v", m, expected)
+	}
+	cm = &ipv4.ControlMessage{IncomingCPU: 2, Truncated: true, GSOSize: 1200}
+	expected = map[string]interface{}{"IncomingCPU": 2, "Truncated": true, "GSOSize": 1200}
+	if m := cm.Fields(); !reflect.DeepEqual(m, expected) {
+		t.Fatalf("got %v; expected %v", m, expected)
+	}
+	txt := time.Date(2015, 1, 2, 3, 4, 5, 6, time.UTC)
+	h := &ipv4.Header{Version: ipv4.Version, Len: ipv4.HeaderLen}
+	cm = &ipv4.ControlMessage{Header: h, MulticastTTL: 8, TxTime: txt, Mark: 0x2a}
+	expected = map[string]interface{}{"Header": h, "MulticastTTL": 8, "TxTime": txt, "Mark": uint32(0x2a)}
+	if m := cm.Fields(); !reflect.DeepEqual(m, expected) {
+		t.Fatalf("got %v; expected %v", m, expected)
+	}
+
+	// Fields and String cover the same set of fields.
+	for _, tt := range controlMessageStringTests {
+		m := tt.cm.Fields()
+		for k := range m {
+			if !strings.Contains(" "+tt.out, " "+k+"=") {
+				t.Errorf("%q: field %s missing in String", tt.out, k)
+			}
+		}
+		if n := strings.Count(tt.out, "="); n != len(m) {
+			t.Errorf("%q: got %v fields; expected %v", tt.out, len(m), n)
+		}
+	}
+}
+
+func TestPacketConnControlMessageFlags(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if err := p.EnableControlMessage(ipv4.FlagTTL); err != nil {
+		t.Fatalf("ipv4.PacketConn.EnableControlMessage failed: %v", err)
+	}
+	if err := p.EnableControlMessage(ipv4.FlagDst); err != nil {
+		t.Fatalf("ipv4.PacketConn.EnableControlMessage failed: %v", err)
+	}
+	if cf := p.ControlMessageFlags(); cf != ipv4.FlagTTL|ipv4.FlagDst {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagTTL|ipv4.FlagDst)
+	}
+	if err := p.DisableControlMessage(ipv4.FlagTTL); err != nil {
+		t.Fatalf("ipv4.PacketConn.DisableControlMessage failed: %v", err)
+	}
+	if cf := p.ControlMessageFlags(); cf != ipv4.FlagDst {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagDst)
+	}
+}
+
+func TestPacketConnSetControlMessage2(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	// FlagRawHeader is never available on UDP endpoints and
+	// FlagTTL is available on all the platforms tested.
+	cf, err := p.SetControlMessage2(ipv4.FlagTTL|ipv4.FlagRawHeader, true)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage2 failed: %v", err)
+	}
+	if cf != ipv4.FlagTTL {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagTTL)
+	}
+	if cf := p.ControlMessageFlags(); cf != ipv4.FlagTTL {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagTTL)
+	}
+	cf, err = p.SetControlMessage2(ipv4.FlagTTL, false)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage2 failed: %v", err)
+	}
+	if cf != ipv4.FlagTTL {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagTTL)
+	}
+	if cf := p.ControlMessageFlags(); cf != 0 {
+		t.Fatalf("got %v; expected 0", cf)
+	}
+}
+
+func TestPacketConnSupportedControlFlags(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	supported := p.SupportedControlFlags()
+	if supported&ipv4.FlagRawHeader != 0 || supported&ipv4.FlagTTL == 0 {
+		t.Fatalf("got %v; expected FlagTTL without FlagRawHeader", supported)
+	}
+	if cf := p.ControlMessageFlags(); cf != 0 {
+		t.Fatalf("got %v; expected 0", cf)
+	}
+	all := ipv4.FlagTTL | ipv4.FlagSrc | ipv4.FlagDst | ipv4.FlagInterface | ipv4.FlagFragSize | ipv4.FlagTOS | ipv4.FlagRawHeader | ipv4.FlagIncomingCPU
+	cf, err := p.SetControlMessage2(all, true)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage2 failed: %v", err)
+	}
+	if cf != supported {
+		t.Fatalf("got %v; expected %v", cf, supported)
+	}
+}
+
+func TestPacketConnRecvCPU(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if runtime.GOOS != "linux" {
+		if err := p.SetRecvCPU(true); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if err := p.SetRecvCPU(true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetRecvCPU failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	_, cm, _, err := p.ReadFrom(make([]byte, 128))
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if cm == nil || cm.IncomingCPU < -1 {
+		t.Fatalf("got %v; expected control message carrying incoming cpu", cm)
+	}
+}
+
+var controlFlagsStringTests = []struct {
+	cf ipv4.ControlFlags
+	s  string
+}{
+	{0, "0"},
+	{ipv4.FlagTTL, "FlagTTL"},
+	{ipv4.FlagTTL | ipv4.FlagDst | ipv4.FlagInterface, "FlagTTL|FlagDst|FlagInterface"},
+	{ipv4.FlagIncomingCPU | ipv4.FlagSrc, "FlagSrc|FlagIncomingCPU"},
+	{ipv4.FlagTOS | 1<<16, "FlagTOS|0x10000"},
+}
+
+func TestControlFlagsString(t *testing.T) {
+	for _, tt := range controlFlagsStringTests {
+		if s := tt.cf.String(); s != tt.s {
+			t.Errorf("got %q; expected %q", s, tt.s)
+		}
+		cf, err := ipv4.ParseControlFlags(tt.s)
+		if err != nil {
+			t.Errorf("ipv4.ParseControlFlags(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if cf != tt.cf {
+			t.Errorf("got %v; expected %v", cf, tt.cf)
+		}
+	}
+}
+
+func TestParseControlFlags(t *testing.T) {
+	for _, s := range []string{"", " ", " FlagTTL | FlagDst "} {
+		if _, err := ipv4.ParseControlFlags(s); err != nil {
+			t.Errorf("ipv4.ParseControlFlags(%q) failed: %v", s, err)
+		}
+	}
+	for _, s := range []string{"FlagTTL|", "flagttl", "FlagTTL,FlagDst", "0xzz"} {
+		if _, err := ipv4.ParseControlFlags(s); err == nil {
+			t.Errorf("ipv4.ParseControlFlags(%q) succeeded; expected error", s)
+		}
+	}
+}
+
+var controlMessageReplyTests = []struct {
+	in, out *ipv4.ControlMessage
+}{
+	{nil, nil},
+	{&ipv4.ControlMessage{}, &ipv4.ControlMessage{}},
+	{
+		&ipv4.ControlMessage{TTL: 64, Src: net.IPv4(192, 0, 2, 1), Dst: net.IPv4(192, 0, 2, 2), IfIndex: 3},
+		&ipv4.ControlMessage{Src: net.IPv4(192, 0, 2, 2).To4(), IfIndex: 3},
+	},
+	{
+		&ipv4.ControlMessage{Dst: net.IPv4(224, 0, 0, 251), IfIndex: 2},
+		&ipv4.ControlMessage{IfIndex: 2},
+	},
+	{
+		&ipv4.ControlMessage{Dst: net.IPv4bcast, IfIndex: 2},
+		&ipv4.ControlMessage{IfIndex: 2},
+	},
+}
+
+func TestControlMessageReply(t *testing.T) {
+	for _, tt := range controlMessageReplyTests {
+		cm := tt.in.Reply()
+		if !reflect.DeepEqual(cm, tt.out) {
+			t.Errorf("got %v; expected %v", cm, tt.out)
+		}
+	}
+}
+
+func TestControlMessageSpace(t *testing.T) {
+	if l := ipv4.ControlMessageSpace(0); l != 0 {
+		t.Fatalf("got %v; expected 0", l)
+	}
+	if l := ipv4.ControlMessageSpace(ipv4.FlagRawHeader | ipv4.FlagIncomingCPU); l != 0 {
+		t.Fatalf("got %v; expected 0", l)
+	}
+	all := ipv4.FlagTTL | ipv4.FlagSrc | ipv4.FlagDst | ipv4.FlagInterface | ipv4.FlagFragSize | ipv4.FlagTOS
+	l := ipv4.ControlMessageSpace(all)
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		if l != 0 {
+			t.Fatalf("got %v; expected 0", l)
+		}
+		return
+	}
+	var sum int
+	for _, cf := range []ipv4.ControlFlags{ipv4.FlagTTL, ipv4.FlagDst | ipv4.FlagInterface, ipv4.FlagFragSize, ipv4.FlagTOS} {
+		sum += ipv4.ControlMessageSpace(cf)
+	}
+	if l != sum || l == 0 {
+		t.Fatalf("got %v; expected %v", l, sum)
+	}
+}
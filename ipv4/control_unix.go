@@ -15,6 +15,15 @@ import (
 )
 
 func setControlMessage(fd int, opt *rawOpt, cf ControlFlags, on bool) error {
+	if cf&FlagFragSize != 0 && sockOpts[ssoReceiveFragSize].name < 1 {
+		return ErrNotSupported
+	}
+	if cf&FlagIncomingCPU != 0 && sockOpts[ssoIncomingCPU].name < 1 {
+		return ErrNotSupported
+	}
+	if cf&FlagTOS != 0 && sockOpts[ssoReceiveTOS].name < 1 {
+		return ErrNotSupported
+	}
 	opt.Lock()
 	defer opt.Unlock()
 	if cf&FlagTTL != 0 && sockOpts[ssoReceiveTTL].name > 0 {
@@ -60,27 +69,109 @@ func setControlMessage(fd int, opt *rawOpt, cf ControlFlags, on bool) error {
 			}
 		}
 	}
+	if cf&FlagFragSize != 0 {
+		if err := setInt(fd, &sockOpts[ssoReceiveFragSize], boolint(on)); err != nil {
+			return err
+		}
+		if on {
+			opt.set(FlagFragSize)
+		} else {
+			opt.clear(FlagFragSize)
+		}
+	}
+	if cf&FlagTOS != 0 {
+		if err := setInt(fd, &sockOpts[ssoReceiveTOS], boolint(on)); err != nil {
+			return err
+		}
+		if on {
+			opt.set(FlagTOS)
+		} else {
+			opt.clear(FlagTOS)
+		}
+	}
+	if cf&FlagRawHeader != 0 {
+		if on {
+			opt.set(FlagRawHeader)
+		} else {
+			opt.clear(FlagRawHeader)
+		}
+	}
+	if cf&FlagIncomingCPU != 0 {
+		if on {
+			opt.set(FlagIncomingCPU)
+		} else {
+			opt.clear(FlagIncomingCPU)
+		}
+	}
 	return nil
 }
 
-func newControlMessage(opt *rawOpt) (oob []byte) {
-	opt.RLock()
+// supportedControlFlags returns the per packet IP-level socket
+// options available on the running platform, derived from the socket
+// option table of the platform.
+func supportedControlFlags() ControlFlags {
+	cf := FlagRawHeader
+	if sockOpts[ssoReceiveTTL].name > 0 {
+		cf |= FlagTTL
+	}
+	if sockOpts[ssoPacketInfo].name > 0 {
+		cf |= FlagSrc | FlagDst | FlagInterface
+	} else {
+		if sockOpts[ssoReceiveDst].name > 0 {
+			cf |= FlagDst
+		}
+		if sockOpts[ssoReceiveInterface].name > 0 {
+			cf |= FlagInterface
+		}
+	}
+	if sockOpts[ssoReceiveFragSize].name > 0 {
+		cf |= FlagFragSize
+	}
+	if sockOpts[ssoReceiveTOS].name > 0 {
+		cf |= FlagTOS
+	}
+	if sockOpts[ssoIncomingCPU].name > 0 {
+		cf |= FlagIncomingCPU
+	}
+	return cf
+}
+
+func controlMessageSpace(cf ControlFlags) int {
 	var l int
-	if opt.isset(FlagTTL) && ctlOpts[ctlTTL].name > 0 {
+	if cf&FlagTTL != 0 && ctlOpts[ctlTTL].name > 0 {
 		l += syscall.CmsgSpace(ctlOpts[ctlTTL].length)
 	}
 	if ctlOpts[ctlPacketInfo].name > 0 {
-		if opt.isset(FlagSrc | FlagDst | FlagInterface) {
+		if cf&(FlagSrc|FlagDst|FlagInterface) != 0 {
 			l += syscall.CmsgSpace(ctlOpts[ctlPacketInfo].length)
 		}
 	} else {
-		if opt.isset(FlagDst) && ctlOpts[ctlDst].name > 0 {
+		if cf&FlagDst != 0 && ctlOpts[ctlDst].name > 0 {
 			l += syscall.CmsgSpace(ctlOpts[ctlDst].length)
 		}
-		if opt.isset(FlagInterface) && ctlOpts[ctlInterface].name > 0 {
+		if cf&FlagInterface != 0 && ctlOpts[ctlInterface].name > 0 {
 			l += syscall.CmsgSpace(ctlOpts[ctlInterface].length)
 		}
 	}
+	if cf&FlagFragSize != 0 && ctlOpts[ctlFragSize].name > 0 {
+		l += syscall.CmsgSpace(ctlOpts[ctlFragSize].length)
+	}
+	if cf&FlagTOS != 0 && ctlOpts[ctlTOS].name > 0 {
+		l += syscall.CmsgSpace(ctlOpts[ctlTOS].length)
+	}
+	return l
+}
+
+func controlMessageTruncated(flags int) bool {
+	return flags&syscall.MSG_CTRUNC != 0
+}
+
+func newControlMessage(opt *rawOpt) (oob []byte) {
+	opt.RLock()
+	l := controlMessageSpace(opt.cflags)
+	if opt.gro {
+		l += groControlMessageSpace
+	}
 	if l > 0 {
 		oob = make([]byte, l)
 		b := oob
@@ -99,6 +190,12 @@ func newControlMessage(opt *rawOpt) (oob []byte) {
 				b = ctlOpts[ctlInterface].marshal(b, nil)
 			}
 		}
+		if opt.isset(FlagFragSize) && ctlOpts[ctlFragSize].name > 0 {
+			b = ctlOpts[ctlFragSize].marshal(b, nil)
+		}
+		if opt.isset(FlagTOS) && ctlOpts[ctlTOS].name > 0 {
+			b = ctlOpts[ctlTOS].marshal(b, nil)
+		}
 	}
 	opt.RUnlock()
 	return
@@ -114,18 +211,24 @@ func parseControlMessage(b []byte) (*ControlMessage, error) {
 	}
 	cm := &ControlMessage{}
 	for _, m := range cmsgs {
+		if parseGRO(cm, int(m.Header.Level), int(m.Header.Type), m.Data) {
+			continue
+		}
 		if m.Header.Level != iana.ProtocolIP {
 			continue
 		}
-		switch int(m.Header.Type) {
-		case ctlOpts[ctlTTL].name:
-			ctlOpts[ctlTTL].parse(cm, m.Data[:])
-		case ctlOpts[ctlDst].name:
-			ctlOpts[ctlDst].parse(cm, m.Data[:])
-		case ctlOpts[ctlInterface].name:
-			ctlOpts[ctlInterface].parse(cm, m.Data[:])
-		case ctlOpts[ctlPacketInfo].name:
-			ctlOpts[ctlPacketInfo].parse(cm, m.Data[:])
+		for i := range ctlOpts {
+			// Send-only options, such as IP_SENDSRCADDR, may
+			// share the value of a receive option.
+			if ctlOpts[i].name < 1 || ctlOpts[i].parse == nil || int(m.Header.Type) != ctlOpts[i].name {
+				continue
+			}
+			// The last control message may be cut short
+			// when the buffer is too small.
+			if len(m.Data) >= ctlOpts[i].length {
+				ctlOpts[i].parse(cm, m.Data[:])
+			}
+			break
 		}
 	}
 	return cm, nil
@@ -136,10 +239,13 @@ func marshalControlMessage(cm *ControlMessage) (oob []byte) {
 		return nil
 	}
 	var l int
-	pktinfo := false
+	pktinfo, src := false, false
 	if ctlOpts[ctlPacketInfo].name > 0 && (cm.Src.To4() != nil || cm.IfIndex > 0) {
 		pktinfo = true
 		l += syscall.CmsgSpace(ctlOpts[ctlPacketInfo].length)
+	} else if ctlOpts[ctlSrc].name > 0 && cm.Src.To4() != nil {
+		src = true
+		l += syscall.CmsgSpace(ctlOpts[ctlSrc].length)
 	}
 	if l > 0 {
 		oob = make([]byte, l)
@@ -147,6 +253,9 @@ func marshalControlMessage(cm *ControlMessage) (oob []byte) {
 		if pktinfo {
 			b = ctlOpts[ctlPacketInfo].marshal(b, cm)
 		}
+		if src {
+			b = ctlOpts[ctlSrc].marshal(b, cm)
+		}
 	}
 	return
 }
@@ -0,0 +1,35 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ipv4
+
+import (
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+func TestParseTruncatedControlMessage(t *testing.T) {
+	if !controlMessageTruncated(syscall.MSG_CTRUNC) || controlMessageTruncated(0) {
+		t.Fatal("MSG_CTRUNC is not detected")
+	}
+
+	// A control message cut short carries no data.
+	b := make([]byte, syscall.CmsgSpace(0))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolIP
+	m.Type = int32(ctlOpts[ctlTTL].name)
+	m.SetLen(syscall.CmsgLen(0))
+	cm, err := parseControlMessage(b)
+	if err != nil {
+		t.Fatalf("parseControlMessage failed: %v", err)
+	}
+	if cm.TTL != 0 {
+		t.Fatalf("got %v; expected 0", cm.TTL)
+	}
+}
@@ -8,7 +8,19 @@ import "syscall"
 
 func setControlMessage(fd syscall.Handle, opt *rawOpt, cf ControlFlags, on bool) error {
 	// TODO(mikio): implement this
-	return syscall.EWINDOWS
+	return ErrNotSupported
+}
+
+func supportedControlFlags() ControlFlags {
+	return 0
+}
+
+func controlMessageSpace(cf ControlFlags) int {
+	return 0
+}
+
+func controlMessageTruncated(flags int) bool {
+	return false
 }
 
 func newControlMessage(opt *rawOpt) []byte {
@@ -18,7 +30,7 @@ func newControlMessage(opt *rawOpt) []byte {
 
 func parseControlMessage(b []byte) (*ControlMessage, error) {
 	// TODO(mikio): implement this
-	return nil, syscall.EWINDOWS
+	return nil, ErrNotSupported
 }
 
 func marshalControlMessage(cm *ControlMessage) []byte {
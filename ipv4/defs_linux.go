@@ -10,7 +10,13 @@ package ipv4
 
 /*
 #include <linux/errqueue.h>
+#include <linux/filter.h>
+#include <linux/icmp.h>
 #include <linux/in.h>
+#include <linux/net_tstamp.h>
+#include <linux/sock_diag.h>
+#include <linux/udp.h>
+#include <sys/socket.h>
 */
 import "C"
 
@@ -37,6 +43,7 @@ const (
 	sysIP_MINTTL          = C.IP_MINTTL
 	sysIP_NODEFRAG        = C.IP_NODEFRAG
 	sysIP_UNICAST_IF      = C.IP_UNICAST_IF
+	sysIP_RECVFRAGSIZE    = C.IP_RECVFRAGSIZE
 
 	sysIP_MULTICAST_IF           = C.IP_MULTICAST_IF
 	sysIP_MULTICAST_TTL          = C.IP_MULTICAST_TTL
@@ -56,6 +63,21 @@ const (
 	sysIP_PMTUDISC_INTERFACE = C.IP_PMTUDISC_INTERFACE
 	sysIP_PMTUDISC_OMIT      = C.IP_PMTUDISC_OMIT
 
+	sysICMP_FILTER = C.ICMP_FILTER
+
+	sysSO_INCOMING_CPU = C.SO_INCOMING_CPU
+	sysSO_TXTIME       = C.SO_TXTIME
+	sysSCM_TXTIME      = C.SCM_TXTIME
+	sysSO_MEMINFO      = C.SO_MEMINFO
+
+	sysSK_MEMINFO_DROPS = C.SK_MEMINFO_DROPS
+	sysSK_MEMINFO_VARS  = C.SK_MEMINFO_VARS
+
+	sysUDP_SEGMENT = C.UDP_SEGMENT
+	sysUDP_GRO     = C.UDP_GRO
+
+	sysSKF_NET_OFF = C.SKF_NET_OFF
+
 	sysSO_EE_ORIGIN_NONE         = C.SO_EE_ORIGIN_NONE
 	sysSO_EE_ORIGIN_LOCAL        = C.SO_EE_ORIGIN_LOCAL
 	sysSO_EE_ORIGIN_ICMP         = C.SO_EE_ORIGIN_ICMP
@@ -69,6 +91,10 @@ const (
 	sysSizeofIPMreq       = C.sizeof_struct_ip_mreq
 	sysSizeofIPMreqn      = C.sizeof_struct_ip_mreqn
 	sysSizeofIPMreqSource = C.sizeof_struct_ip_mreq_source
+
+	sysSizeofICMPFilter = C.sizeof_struct_icmp_filter
+
+	sysSizeofSockTxtime = C.sizeof_struct_sock_txtime
 )
 
 type sysInetPktinfo C.struct_in_pktinfo
@@ -80,3 +106,7 @@ type sysIPMreq C.struct_ip_mreq
 type sysIPMreqn C.struct_ip_mreqn
 
 type sysIPMreqSource C.struct_ip_mreq_source
+
+type sysICMPFilter C.struct_icmp_filter
+
+type sysSockTxtime C.struct_sock_txtime
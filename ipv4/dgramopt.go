@@ -0,0 +1,139 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"sync"
+)
+
+// A membership represents a group membership on an interface.
+type membership struct {
+	ifindex int // zero for the system assigned interface
+	group   [net.IPv4len]byte
+}
+
+// memberships records the group memberships made through an
+// endpoint.  The protocol stack is authoritative on the memberships
+// of the socket; the records only serve for leaving the groups on
+// close and for rejoining them on Swap.
+type memberships struct {
+	sync.Mutex
+	m map[membership]*net.Interface
+}
+
+// A NotMulticastError reports a group address that is not a
+// multicast address.  It matches ErrNotMulticast with errors.Is.
+type NotMulticastError struct {
+	Addr net.IP // group address
+}
+
+func (e *NotMulticastError) Error() string { return ErrNotMulticast.Error() + ": " + e.Addr.String() }
+
+// Is reports whether target is ErrNotMulticast.
+func (e *NotMulticastError) Is(target error) bool { return target == ErrNotMulticast }
+
+func newMembership(ifi *net.Interface, grp net.IP) membership {
+	var m membership
+	if ifi != nil {
+		m.ifindex = ifi.Index
+	}
+	copy(m.group[:], grp.To4())
+	return m
+}
+
+func (ms *memberships) add(ifi *net.Interface, grp net.IP) {
+	if ms.m == nil {
+		ms.m = make(map[membership]*net.Interface)
+	}
+	ms.m[newMembership(ifi, grp)] = ifi
+}
+
+func (ms *memberships) remove(ifi *net.Interface, grp net.IP) {
+	delete(ms.m, newMembership(ifi, grp))
+}
+
+// JoinGroupAddr is like JoinGroup but takes the name of the
+// interface and the textual representation of the group address,
+// such as "eth0" and "239.1.2.3".
+// It uses the system assigned multicast interface when ifName is
+// empty.
+func (c *dgramOpt) JoinGroupAddr(ifName, group string) error {
+	ifi, grp, err := parseGroup(ifName, group)
+	if err != nil {
+		return err
+	}
+	return c.JoinGroup(ifi, grp)
+}
+
+// LeaveGroupAddr is like LeaveGroup but takes the name of the
+// interface and the textual representation of the group address.
+func (c *dgramOpt) LeaveGroupAddr(ifName, group string) error {
+	ifi, grp, err := parseGroup(ifName, group)
+	if err != nil {
+		return err
+	}
+	return c.LeaveGroup(ifi, grp)
+}
+
+// parseGroup returns the interface named ifName and the IPv4 group
+// address represented by group.  The returned interface is nil when
+// ifName is empty.
+func parseGroup(ifName, group string) (*net.Interface, net.Addr, error) {
+	ip := net.ParseIP(group)
+	if ip == nil {
+		return nil, nil, &net.AddrError{Err: "invalid group address", Addr: group}
+	}
+	if ip = ip.To4(); ip == nil {
+		return nil, nil, errNonIPv4Address
+	}
+	if ifName == "" {
+		return nil, &net.IPAddr{IP: ip}, nil
+	}
+	ifi, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ifi, &net.IPAddr{IP: ip}, nil
+}
+
+// BroadcastAddr returns the directed broadcast address of the
+// network specified by the IPv4 address ip and the network mask mask.
+// It returns nil if ip is not an IPv4 address or mask is not an IPv4
+// network mask.
+func BroadcastAddr(ip net.IP, mask net.IPMask) net.IP {
+	ip = ip.To4()
+	if ip == nil {
+		return nil
+	}
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	if len(mask) != net.IPv4len {
+		return nil
+	}
+	b := make(net.IP, net.IPv4len)
+	for i := range b {
+		b[i] = ip[i] | ^mask[i]
+	}
+	return b
+}
+
+// IsLinkLocalMulticast reports whether ip is an IPv4 link-local
+// multicast address in 224.0.0.0/24, the Local Network Control Block
+// defined in RFC 5771, which is never forwarded by routers.
+func IsLinkLocalMulticast(ip net.IP) bool {
+	ip = ip.To4()
+	return ip != nil && ip[0] == 224 && ip[1] == 0 && ip[2] == 0
+}
+
+// IsAdminScopedMulticast reports whether ip is an IPv4
+// administratively scoped multicast address in 239.0.0.0/8, as
+// defined in RFC 2365, whose scope boundaries are configured by the
+// network administrator.
+func IsAdminScopedMulticast(ip net.IP) bool {
+	ip = ip.To4()
+	return ip != nil && ip[0] == 239
+}
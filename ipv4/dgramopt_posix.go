@@ -13,6 +13,7 @@ import (
 
 // MulticastTTL returns the time-to-live field value for outgoing
 // multicast packets.
+// The value is queried from the protocol stack on each call.
 func (c *dgramOpt) MulticastTTL() (int, error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
@@ -63,6 +64,53 @@ func (c *dgramOpt) SetMulticastInterface(ifi *net.Interface) error {
 	return setInterface(fd, &sockOpts[ssoMulticastInterface], ifi)
 }
 
+// SetMulticastInterfaceAddr sets the default interface for future
+// multicast packet transmissions by its IPv4 address ip, which must
+// be assigned to a local interface.  Unlike SetMulticastInterface, it
+// selects the source address of the outgoing packets when the
+// interface has multiple addresses.
+func (c *dgramOpt) SetMulticastInterfaceAddr(ip net.IP) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	ip = ip.To4()
+	if ip == nil {
+		return errNonIPv4Address
+	}
+	ok, err := isLocalIP4(ip)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNonLocalAddress
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInterfaceAddr(fd, &sockOpts[ssoMulticastInterface], ip)
+}
+
+func isLocalIP4(ip net.IP) (bool, error) {
+	ifat, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+	for _, ifa := range ifat {
+		switch ifa := ifa.(type) {
+		case *net.IPAddr:
+			if ip.Equal(ifa.IP) {
+				return true, nil
+			}
+		case *net.IPNet:
+			if ip.Equal(ifa.IP) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // MulticastLoopback reports whether transmitted multicast packets
 // should be copied and send back to the originator.
 func (c *dgramOpt) MulticastLoopback() (bool, error) {
@@ -93,37 +141,177 @@ func (c *dgramOpt) SetMulticastLoopback(on bool) error {
 	return setInt(fd, &sockOpts[ssoMulticastLoopback], boolint(on))
 }
 
+// Broadcast reports whether transmission of datagrams to broadcast
+// addresses is permitted.
+func (c *dgramOpt) Broadcast() (bool, error) {
+	if !c.ok() {
+		return false, syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return false, err
+	}
+	on, err := getInt(fd, &sockOpts[ssoBroadcast])
+	if err != nil {
+		return false, err
+	}
+	return on != 0, nil
+}
+
+// SetBroadcast sets whether transmission of datagrams to broadcast
+// addresses is permitted.
+func (c *dgramOpt) SetBroadcast(on bool) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInt(fd, &sockOpts[ssoBroadcast], boolint(on))
+}
+
+// DontRoute reports whether outgoing datagrams bypass the routing
+// table and are sent only to destinations on directly attached
+// networks.
+// Windows doesn't support this.
+func (c *dgramOpt) DontRoute() (bool, error) {
+	if !c.ok() {
+		return false, syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return false, err
+	}
+	on, err := getInt(fd, &sockOpts[ssoDontRoute])
+	if err != nil {
+		return false, err
+	}
+	return on != 0, nil
+}
+
+// SetDontRoute sets whether outgoing datagrams bypass the routing
+// table.  When on, the protocol stack sends datagrams only through
+// the interface attached to the network of the destination, and
+// fails when no such interface exists.
+// Windows doesn't support this.
+func (c *dgramOpt) SetDontRoute(on bool) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInt(fd, &sockOpts[ssoDontRoute], boolint(on))
+}
+
+// ICMPFilter returns an ICMP filter.
+// Currently only Linux supports this.
+func (c *dgramOpt) ICMPFilter() (*ICMPFilter, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return nil, err
+	}
+	return getICMPFilter(fd, &sockOpts[ssoICMPFilter])
+}
+
+// SetICMPFilter deploys the ICMP filter.
+// Currently only Linux supports this.
+func (c *dgramOpt) SetICMPFilter(f *ICMPFilter) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setICMPFilter(fd, &sockOpts[ssoICMPFilter], f)
+}
+
 // JoinGroup joins the group address group on the interface ifi.
 // It uses the system assigned multicast interface when ifi is nil,
 // although this is not recommended because the assignment depends on
 // platforms and sometimes it might require routing configuration.
+// It returns *NotMulticastError, which matches ErrNotMulticast, when
+// group is not in 224.0.0.0/4.
+//
+// JoinGroup and LeaveGroup are safe for concurrent use by multiple
+// goroutines; the membership changes on the endpoint are serialized,
+// also with Swap.  The package records the groups joined only to
+// leave them in CloseGraceful and to rejoin them in Swap; the
+// protocol stack remains authoritative on the memberships of the
+// socket.  Note that joining a group already joined on the same
+// interface, or leaving a group not joined, fails with an error from
+// the protocol stack.
 func (c *dgramOpt) JoinGroup(ifi *net.Interface, group net.Addr) error {
 	if !c.ok() {
 		return syscall.EINVAL
 	}
+	grp := netAddrToIP4(group)
+	if grp == nil {
+		return ErrMissingAddress
+	}
+	if !grp.IsMulticast() {
+		return &NotMulticastError{Addr: grp}
+	}
+	c.groups.Lock()
+	defer c.groups.Unlock()
 	fd, err := c.sysfd()
 	if err != nil {
 		return err
 	}
-	grp := netAddrToIP4(group)
-	if grp == nil {
-		return errMissingAddress
+	if err := setGroup(fd, &sockOpts[ssoJoinGroup], ifi, grp); err != nil {
+		return err
 	}
-	return setGroup(fd, &sockOpts[ssoJoinGroup], ifi, grp)
+	c.groups.add(ifi, grp)
+	return nil
 }
 
 // LeaveGroup leaves the group address group on the interface ifi.
+// It returns *NotMulticastError when group is not a multicast
+// address, as JoinGroup does.
 func (c *dgramOpt) LeaveGroup(ifi *net.Interface, group net.Addr) error {
 	if !c.ok() {
 		return syscall.EINVAL
 	}
+	grp := netAddrToIP4(group)
+	if grp == nil {
+		return ErrMissingAddress
+	}
+	if !grp.IsMulticast() {
+		return &NotMulticastError{Addr: grp}
+	}
+	c.groups.Lock()
+	defer c.groups.Unlock()
 	fd, err := c.sysfd()
 	if err != nil {
 		return err
 	}
-	grp := netAddrToIP4(group)
-	if grp == nil {
-		return errMissingAddress
+	if err := setGroup(fd, &sockOpts[ssoLeaveGroup], ifi, grp); err != nil {
+		return err
+	}
+	c.groups.remove(ifi, grp)
+	return nil
+}
+
+// leaveAllGroups leaves all the groups recorded by JoinGroup,
+// ignoring failures.
+func (c *dgramOpt) leaveAllGroups() {
+	if !c.ok() {
+		return
+	}
+	c.groups.Lock()
+	defer c.groups.Unlock()
+	fd, err := c.sysfd()
+	if err != nil {
+		return
+	}
+	for m, ifi := range c.groups.m {
+		setGroup(fd, &sockOpts[ssoLeaveGroup], ifi, net.IP(m.group[:]))
+		delete(c.groups.m, m)
 	}
-	return setGroup(fd, &sockOpts[ssoLeaveGroup], ifi, grp)
 }
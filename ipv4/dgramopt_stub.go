@@ -9,33 +9,63 @@ package ipv4
 import "net"
 
 func (c *dgramOpt) MulticastTTL() (int, error) {
-	return 0, errOpNoSupport
+	return 0, ErrNotSupported
 }
 
 func (c *dgramOpt) SetMulticastTTL(ttl int) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
 
 func (c *dgramOpt) MulticastInterface() (*net.Interface, error) {
-	return nil, errOpNoSupport
+	return nil, ErrNotSupported
 }
 
 func (c *dgramOpt) SetMulticastInterface(ifi *net.Interface) error {
-	return errOpNoSupport
+	return ErrNotSupported
+}
+
+func (c *dgramOpt) SetMulticastInterfaceAddr(ip net.IP) error {
+	return ErrNotSupported
 }
 
 func (c *dgramOpt) MulticastLoopback() (bool, error) {
-	return false, errOpNoSupport
+	return false, ErrNotSupported
 }
 
 func (c *dgramOpt) SetMulticastLoopback(on bool) error {
-	return errOpNoSupport
+	return ErrNotSupported
+}
+
+func (c *dgramOpt) Broadcast() (bool, error) {
+	return false, ErrNotSupported
+}
+
+func (c *dgramOpt) SetBroadcast(on bool) error {
+	return ErrNotSupported
+}
+
+func (c *dgramOpt) DontRoute() (bool, error) {
+	return false, ErrNotSupported
+}
+
+func (c *dgramOpt) SetDontRoute(on bool) error {
+	return ErrNotSupported
+}
+
+func (c *dgramOpt) ICMPFilter() (*ICMPFilter, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *dgramOpt) SetICMPFilter(f *ICMPFilter) error {
+	return ErrNotSupported
 }
 
 func (c *dgramOpt) JoinGroup(ifi *net.Interface, grp net.Addr) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
 
 func (c *dgramOpt) LeaveGroup(ifi *net.Interface, grp net.Addr) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
+
+func (c *dgramOpt) leaveAllGroups() {}
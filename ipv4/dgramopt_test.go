@@ -0,0 +1,123 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"testing"
+)
+
+var parseGroupTests = []struct {
+	group string
+	out   net.IP
+	ok    bool
+}{
+	{"239.1.2.3", net.IP{239, 1, 2, 3}, true},
+	{"::ffff:224.0.0.251", net.IP{224, 0, 0, 251}, true},
+
+	{"", nil, false},
+	{"239.1.2", nil, false},
+	{"ff02::fb", nil, false},
+}
+
+func TestParseGroup(t *testing.T) {
+	for _, tt := range parseGroupTests {
+		ifi, grp, err := parseGroup("", tt.group)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("parseGroup(%q) succeeded; expected error", tt.group)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGroup(%q) failed: %v", tt.group, err)
+			continue
+		}
+		if ifi != nil {
+			t.Errorf("got %v; expected nil interface", ifi)
+		}
+		if ip := grp.(*net.IPAddr).IP; !ip.Equal(tt.out) || len(ip) != net.IPv4len {
+			t.Errorf("got %v; expected %v", ip, tt.out)
+		}
+	}
+
+	if _, _, err := parseGroup("no-such-interface0", "239.1.2.3"); err == nil {
+		t.Error("parseGroup succeeded with unknown interface; expected error")
+	}
+}
+
+var broadcastAddrTests = []struct {
+	ip   net.IP
+	mask net.IPMask
+	out  net.IP
+}{
+	{net.IPv4(192, 0, 2, 1), net.CIDRMask(24, 32), net.IP{192, 0, 2, 255}},
+	{net.IP{10, 1, 2, 3}, net.CIDRMask(8, 32), net.IP{10, 255, 255, 255}},
+	{net.IP{198, 51, 100, 77}, net.CIDRMask(30, 32), net.IP{198, 51, 100, 79}},
+	{net.IP{192, 0, 2, 1}, net.IPMask(net.IPv4(255, 255, 255, 0)), net.IP{192, 0, 2, 255}},
+
+	{net.ParseIP("2001:db8::1"), net.CIDRMask(64, 128), nil},
+	{net.IP{192, 0, 2, 1}, net.IPMask{255, 255}, nil},
+}
+
+func TestBroadcastAddr(t *testing.T) {
+	for _, tt := range broadcastAddrTests {
+		ip := BroadcastAddr(tt.ip, tt.mask)
+		if tt.out == nil {
+			if ip != nil {
+				t.Errorf("BroadcastAddr(%v, %v) = %v; expected nil", tt.ip, tt.mask, ip)
+			}
+			continue
+		}
+		if !ip.Equal(tt.out) {
+			t.Errorf("BroadcastAddr(%v, %v) = %v; expected %v", tt.ip, tt.mask, ip, tt.out)
+		}
+	}
+}
+
+func TestMemberships(t *testing.T) {
+	var ms memberships
+	ifi := &net.Interface{Index: 1, Name: "lo"}
+	grp := net.IPv4(239, 255, 0, 1)
+	ms.add(ifi, grp)
+	ms.add(nil, grp)
+	ms.add(ifi, grp.To4())
+	if len(ms.m) != 2 {
+		t.Fatalf("got %v memberships; expected 2", len(ms.m))
+	}
+	ms.remove(&net.Interface{Index: 1}, grp.To4())
+	if len(ms.m) != 1 {
+		t.Fatalf("got %v memberships; expected 1", len(ms.m))
+	}
+	if ifi, ok := ms.m[newMembership(nil, grp)]; !ok || ifi != nil {
+		t.Fatalf("got %v, %v; expected <nil>, true", ifi, ok)
+	}
+}
+
+var multicastScopeTests = []struct {
+	ip                     net.IP
+	linkLocal, adminScoped bool
+}{
+	{net.IPv4(224, 0, 0, 1), true, false},
+	{net.IPv4(224, 0, 0, 251), true, false},
+	{net.IPv4(224, 0, 1, 1), false, false},
+	{net.IPv4(239, 255, 255, 250), false, true},
+	{net.IPv4(239, 0, 0, 0), false, true},
+	{net.IPv4(238, 255, 255, 255), false, false},
+	{net.IPv4(192, 168, 0, 1), false, false},
+	{net.ParseIP("ff02::1"), false, false},
+	{nil, false, false},
+}
+
+func TestMulticastScope(t *testing.T) {
+	for _, tt := range multicastScopeTests {
+		if v := IsLinkLocalMulticast(tt.ip); v != tt.linkLocal {
+			t.Errorf("IsLinkLocalMulticast(%v) = %v; expected %v", tt.ip, v, tt.linkLocal)
+		}
+		if v := IsAdminScopedMulticast(tt.ip); v != tt.adminScoped {
+			t.Errorf("IsAdminScopedMulticast(%v) = %v; expected %v", tt.ip, v, tt.adminScoped)
+		}
+	}
+}
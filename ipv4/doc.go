@@ -116,6 +116,14 @@
 //			}
 //		}
 //
+// Note that the control message carries IP-level information only.
+// Link-layer information such as the IEEE 802.1Q VLAN identifier and
+// the IEEE 802.1p priority of the received frame is not available on
+// IP-level sockets on any platform; for example, Linux strips the tag
+// before the packet reaches the IP layer and passes it only to
+// AF_PACKET sockets as PACKET_AUXDATA, which are out of scope of the
+// package.
+//
 // The application can also send both unicast and multicast packets.
 //
 //		p.SetTOS(DiffServCS0)
@@ -193,4 +201,50 @@
 //	if err := p.JoinGroup(en0, &net.UDPAddr{IP: net.IPv4(224, 0, 0, 250)}); err != nil {
 //		// error handling
 //	}
+//
+//
+// Concurrency
+//
+// The methods of Conn, PacketConn and RawConn are safe for concurrent
+// use by multiple goroutines.  The methods that set or get socket
+// options, such as SetTTL and TTL, keep no copy of the options in the
+// package and pass through to the protocol stack, which serializes
+// the accesses to them; a goroutine calling TTL after another's
+// SetTTL returns observes the new value.  The states kept in the
+// package, such as the per packet socket options turned on by
+// SetControlMessage and the groups joined through JoinGroup, are
+// protected by locks.  Note that an option set by one goroutine
+// affects the packets written by others, so a goroutine wanting an
+// option only for its own packets should use the fields of the
+// control message passed to WriteTo where available.
+//
+//
+// Errors
+//
+// Errors returned by the ReadFrom and WriteTo methods of PacketConn
+// and RawConn fall into two classes.  Errors detected by the package
+// itself before or after any system call, such as ErrMissingAddress,
+// ErrInvalidConnType or ErrHeaderTooShort, are returned as is and
+// may be compared against the exported variables directly.  Errors
+// reported by the underlying system calls are returned as
+// *net.OpError, whose Err field holds an *os.SyscallError wrapping
+// the syscall.Errno, regardless of the version of the standard
+// library.  Timeouts are reported by *net.OpError as well, but not
+// through *os.SyscallError.
+//
+//	_, err := p.WriteTo(b, nil, dst)
+//	if err, ok := err.(*net.OpError); ok {
+//		if serr, ok := err.Err.(*os.SyscallError); ok && serr.Err == syscall.ENOBUFS {
+//			// back off and retry
+//		}
+//	}
+//
+// The errnos commonly seen from WriteTo include EMSGSIZE when the
+// datagram exceeds the path or interface MTU and fragmentation is not
+// permitted, EPERM when the transmission is rejected by a packet
+// filter or the endpoint lacks the privilege, ENETUNREACH or
+// EHOSTUNREACH when no route to the destination exists, and ENOBUFS
+// when the outgoing interface queue is full.  For EMSGSIZE, the Err
+// field of *net.OpError returned by PacketConn may hold *MTUError
+// instead, which carries the path MTU and wraps the *os.SyscallError.
 package ipv4
@@ -0,0 +1,147 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+var errNotEchoReply = errors.New("not echo reply")
+
+// NewEchoRequest returns the binary encoding of an ICMP echo request
+// message that carries the identifier id, the sequence number seq
+// and payload.  The returned message contains the calculated
+// checksum field and is ready to be passed to WriteTo method of
+// PacketConn on ICMP endpoints.
+func NewEchoRequest(id, seq int, payload []byte) ([]byte, error) {
+	if id < 0 || id > 0xffff || seq < 0 || seq > 0xffff {
+		return nil, errors.New("invalid identifier or sequence number")
+	}
+	b := make([]byte, 8+len(payload))
+	b[0] = byte(ICMPTypeEcho)
+	b[4], b[5] = byte(id>>8), byte(id)
+	b[6], b[7] = byte(seq>>8), byte(seq)
+	copy(b[8:], payload)
+	s := checksum(b)
+	b[2], b[3] = byte(s>>8), byte(s)
+	return b, nil
+}
+
+// NewSizedEchoRequest is like NewEchoRequest but returns an ICMP
+// echo request message whose IPv4 datagram is exactly totalLen octets
+// long, which is useful for probing the path MTU.  The length of the
+// payload, which consists of octets counting up from zero, is
+// calculated assuming that the IPv4 header carries no options, that
+// is totalLen minus HeaderLen and the 8-octet ICMP header.
+func NewSizedEchoRequest(id, seq, totalLen int) ([]byte, error) {
+	if totalLen < HeaderLen+8 || totalLen > 0xffff {
+		return nil, errInvalidTotalLen
+	}
+	payload := make([]byte, totalLen-HeaderLen-8)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	return NewEchoRequest(id, seq, payload)
+}
+
+// PatchEchoSeq overwrites the sequence number field of the ICMP echo
+// message b, such as the one returned by NewEchoRequest, with seq and
+// updates the checksum incrementally as described in RFC 1624.  It
+// allows the application to send a series of echo requests from a
+// single buffer without marshaling each of them.  The b must be at
+// least 8 bytes long, and seq is truncated to 16 bits.
+func PatchEchoSeq(b []byte, seq int) {
+	old := uint16(b[6])<<8 | uint16(b[7])
+	b[6], b[7] = byte(seq>>8), byte(seq)
+	cs := adjustChecksum(uint16(b[2])<<8|uint16(b[3]), old, uint16(seq))
+	b[2], b[3] = byte(cs>>8), byte(cs)
+}
+
+// ParseEchoReply parses b as an ICMP echo reply message and returns
+// its identifier, sequence number and payload.  The returned payload
+// shares the underlying array with b.
+func ParseEchoReply(b []byte) (id, seq int, payload []byte, err error) {
+	if len(b) < 8 {
+		return 0, 0, nil, errBufferTooShort
+	}
+	if ICMPType(b[0]) != ICMPTypeEchoReply || b[1] != 0 {
+		return 0, 0, nil, errNotEchoReply
+	}
+	if checksum(b) != 0 {
+		return 0, 0, nil, ErrInvalidChecksum
+	}
+	return int(b[4])<<8 | int(b[5]), int(b[6])<<8 | int(b[7]), b[8:], nil
+}
+
+// An EchoSender builds a series of ICMP echo request messages that
+// share an identifier and carry monotonically increasing sequence
+// numbers, and correlates ICMP echo reply messages with them.  It
+// takes care of the bookkeeping of a ping loop on top of
+// NewEchoRequest and ParseEchoReply.
+// It is safe for concurrent use by multiple goroutines.
+type EchoSender struct {
+	mu   sync.Mutex
+	id   int // identifier
+	seq  int // next sequence number
+	sent int // number of outstanding sequence numbers, up to 1<<16
+}
+
+// NewEchoSender returns a new EchoSender.  The identifier is derived
+// from the process ID.
+func NewEchoSender() *EchoSender {
+	return &EchoSender{id: os.Getpid() & 0xffff}
+}
+
+// ID returns the identifier of echo request messages.
+func (e *EchoSender) ID() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.id
+}
+
+// SetID sets the identifier of future echo request messages and
+// restarts the sequence numbers from zero.  It is useful for
+// non-privileged datagram-oriented ICMP endpoints, where the kernel
+// assigns the identifier; see ICMPEchoID.
+func (e *EchoSender) SetID(id int) {
+	e.mu.Lock()
+	e.id = id & 0xffff
+	e.seq, e.sent = 0, 0
+	e.mu.Unlock()
+}
+
+// Next returns the binary encoding of a new ICMP echo request message
+// that carries data and the next sequence number, along with the
+// sequence number.  The sequence number wraps around at 1<<16.
+func (e *EchoSender) Next(data []byte) (b []byte, seq int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	seq = e.seq
+	b, _ = NewEchoRequest(e.id, seq, data) // id and seq are within 16 bits
+	e.seq = (e.seq + 1) & 0xffff
+	if e.sent < 1<<16 {
+		e.sent++
+	}
+	return b, seq
+}
+
+// Match parses b as an ICMP echo reply message and reports whether it
+// corresponds to one of the echo request messages returned by Next,
+// along with its sequence number and payload.  The returned payload
+// shares the underlying array with b.
+func (e *EchoSender) Match(b []byte) (seq int, payload []byte, ok bool) {
+	id, seq, payload, err := ParseEchoReply(b)
+	if err != nil {
+		return 0, nil, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if id != e.id || (e.seq-seq-1)&0xffff >= e.sent {
+		return 0, nil, false
+	}
+	return seq, payload, true
+}
@@ -0,0 +1,171 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/internal/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+func TestNewEchoRequest(t *testing.T) {
+	for _, payload := range [][]byte{nil, []byte("HELLO-R-U-THERE"), []byte("HELLO-R-U-THERE?")} {
+		b, err := ipv4.NewEchoRequest(0x1234, 0x5678, payload)
+		if err != nil {
+			t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+		}
+		m, err := icmp.ParseMessage(iana.ProtocolICMP, b)
+		if err != nil {
+			t.Fatalf("icmp.ParseMessage failed: %v", err)
+		}
+		// Marshal again to verify the checksum computed by
+		// NewEchoRequest.
+		wb, err := m.Marshal(nil)
+		if err != nil {
+			t.Fatalf("icmp.Message.Marshal failed: %v", err)
+		}
+		if !bytes.Equal(b, wb) {
+			t.Fatalf("got %#v; expected %#v", b, wb)
+		}
+		expected := &icmp.Echo{ID: 0x1234, Seq: 0x5678, Data: payload}
+		if m.Type != ipv4.ICMPTypeEcho || !reflect.DeepEqual(m.Body, expected) {
+			t.Fatalf("got %v %#v; expected %v %#v", m.Type, m.Body, ipv4.ICMPTypeEcho, expected)
+		}
+	}
+	if _, err := ipv4.NewEchoRequest(1<<16, 0, nil); err == nil {
+		t.Fatal("ipv4.NewEchoRequest succeeded with out of range identifier")
+	}
+}
+
+func TestParseEchoReply(t *testing.T) {
+	b, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEchoReply, Code: 0,
+		Body: &icmp.Echo{ID: 0x1234, Seq: 0x5678, Data: []byte("HELLO-R-U-THERE")},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatalf("icmp.Message.Marshal failed: %v", err)
+	}
+	id, seq, payload, err := ipv4.ParseEchoReply(b)
+	if err != nil {
+		t.Fatalf("ipv4.ParseEchoReply failed: %v", err)
+	}
+	if id != 0x1234 || seq != 0x5678 || string(payload) != "HELLO-R-U-THERE" {
+		t.Fatalf("got id=%#x, seq=%#x, payload=%q", id, seq, payload)
+	}
+
+	b[len(b)-1] ^= 0xff
+	if _, _, _, err := ipv4.ParseEchoReply(b); err != ipv4.ErrInvalidChecksum {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrInvalidChecksum)
+	}
+	req, err := ipv4.NewEchoRequest(1, 1, nil)
+	if err != nil {
+		t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+	}
+	if _, _, _, err := ipv4.ParseEchoReply(req); err == nil {
+		t.Fatal("ipv4.ParseEchoReply succeeded with echo request")
+	}
+}
+
+func TestNewSizedEchoRequest(t *testing.T) {
+	for _, totalLen := range []int{ipv4.HeaderLen + 8, 576, 1500} {
+		b, err := ipv4.NewSizedEchoRequest(0x1234, 0x5678, totalLen)
+		if err != nil {
+			t.Fatalf("ipv4.NewSizedEchoRequest failed: %v", err)
+		}
+		if ipv4.HeaderLen+len(b) != totalLen {
+			t.Fatalf("got %v; expected %v", ipv4.HeaderLen+len(b), totalLen)
+		}
+		if _, err := icmp.ParseMessage(iana.ProtocolICMP, b); err != nil {
+			t.Fatalf("icmp.ParseMessage failed: %v", err)
+		}
+	}
+	for _, totalLen := range []int{-1, ipv4.HeaderLen + 7, 0x10000} {
+		if _, err := ipv4.NewSizedEchoRequest(0x1234, 0x5678, totalLen); err == nil {
+			t.Fatalf("ipv4.NewSizedEchoRequest succeeded with %v", totalLen)
+		}
+	}
+}
+
+func TestPatchEchoSeq(t *testing.T) {
+	b, err := ipv4.NewEchoRequest(0x1234, 0, []byte("HELLO-R-U-THERE"))
+	if err != nil {
+		t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+	}
+	for _, seq := range []int{1, 2, 0x5678, 0xffff, 0} {
+		ipv4.PatchEchoSeq(b, seq)
+		wb, err := ipv4.NewEchoRequest(0x1234, seq, []byte("HELLO-R-U-THERE"))
+		if err != nil {
+			t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+		}
+		if !bytes.Equal(b, wb) {
+			t.Fatalf("got %#v; expected %#v", b, wb)
+		}
+		m, err := icmp.ParseMessage(iana.ProtocolICMP, b)
+		if err != nil {
+			t.Fatalf("icmp.ParseMessage failed: %v", err)
+		}
+		if p, ok := m.Body.(*icmp.Echo); !ok || p.Seq != seq {
+			t.Fatalf("got %#v; expected sequence number %v", m.Body, seq)
+		}
+	}
+}
+
+func TestEchoSender(t *testing.T) {
+	e := ipv4.NewEchoSender()
+	for _, id := range []int{e.ID(), 0xfeed} {
+		e.SetID(id)
+		if e.ID() != id {
+			t.Fatalf("got %v; expected %v", e.ID(), id)
+		}
+		var reqs [][]byte
+		for i := 0; i < 3; i++ {
+			b, seq := e.Next([]byte("HELLO-R-U-THERE"))
+			if seq != i {
+				t.Fatalf("got seq=%v; expected %v", seq, i)
+			}
+			m, err := icmp.ParseMessage(iana.ProtocolICMP, b)
+			if err != nil {
+				t.Fatalf("icmp.ParseMessage failed: %v", err)
+			}
+			p, ok := m.Body.(*icmp.Echo)
+			if m.Type != ipv4.ICMPTypeEcho || !ok || p.ID != id || p.Seq != i {
+				t.Fatalf("got %v %#v; expected echo request with id=%v, seq=%v", m.Type, m.Body, id, i)
+			}
+			reqs = append(reqs, b)
+		}
+		for i := range reqs {
+			rep := echoReply(t, id, i, []byte("HELLO-R-U-THERE"))
+			seq, payload, ok := e.Match(rep)
+			if !ok || seq != i || string(payload) != "HELLO-R-U-THERE" {
+				t.Errorf("got seq=%v, payload=%q, ok=%v; expected seq=%v", seq, payload, ok, i)
+			}
+		}
+		for _, b := range [][]byte{
+			reqs[0],
+			echoReply(t, id^1, 0, nil),
+			echoReply(t, id, 3, nil),
+			nil,
+		} {
+			if _, _, ok := e.Match(b); ok {
+				t.Errorf("%#v matched unexpectedly", b)
+			}
+		}
+	}
+}
+
+func echoReply(t *testing.T, id, seq int, data []byte) []byte {
+	b, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEchoReply, Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: data},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatalf("icmp.Message.Marshal failed: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import "errors"
+
+var errNotEncapsulated = errors.New("not encapsulated packet")
+
+const (
+	protocolIPIP = 4  // ipv4 encapsulation, see RFC 2003
+	protocolGRE  = 47 // generic routing encapsulation, see RFC 2784
+)
+
+const greHeaderLen = 4 // gre header length without optional fields
+
+// EncapsulateIPIP returns the IPv4-in-IPv4 encapsulation, described
+// in RFC 2003, of the IPv4 datagram inner in wire format with the
+// outer IPv4 header outer.  It sets the Len, TotalLen, Protocol and
+// Checksum fields of outer, while the other fields, such as TTL, are
+// taken from outer as is, so that the outer header is controlled
+// independently of the inner one.  The returned datagram is in wire
+// format, such as for writing to a file, and the outer header and
+// the payload may be passed separately to the WriteTo method of
+// RawConn instead.
+func EncapsulateIPIP(outer *Header, inner []byte) ([]byte, error) {
+	if outer == nil {
+		return nil, ErrMissingHeader
+	}
+	if _, _, err := slicePacket(inner); err != nil {
+		return nil, err
+	}
+	if inner[0]>>4 != Version {
+		return nil, errNotEncapsulated
+	}
+	return encapsulate(outer, protocolIPIP, inner)
+}
+
+// DecapsulateIPIP parses b as an IPv4-in-IPv4 encapsulated datagram
+// in wire format and returns the outer IPv4 header and the inner
+// IPv4 datagram, which shares the underlying array with b.
+func DecapsulateIPIP(b []byte) (outer *Header, inner []byte, err error) {
+	if outer, inner, err = decapsulate(b, protocolIPIP); err != nil {
+		return nil, nil, err
+	}
+	if _, _, err := slicePacket(inner); err != nil {
+		return nil, nil, err
+	}
+	if inner[0]>>4 != Version {
+		return nil, nil, errNotEncapsulated
+	}
+	return outer, inner, nil
+}
+
+// EncapsulateGRE returns the Generic Routing Encapsulation, described
+// in RFC 2784, of payload with the outer IPv4 header outer.  The
+// protocolType is the EtherType of payload, such as 0x0800 for IPv4.
+// The GRE header carries no optional fields; neither the checksum,
+// the key nor the sequence number is present.  Like EncapsulateIPIP,
+// it sets the Len, TotalLen, Protocol and Checksum fields of outer,
+// and returns the datagram in wire format.
+func EncapsulateGRE(outer *Header, protocolType uint16, payload []byte) ([]byte, error) {
+	if outer == nil {
+		return nil, ErrMissingHeader
+	}
+	p := make([]byte, greHeaderLen+len(payload))
+	p[2], p[3] = byte(protocolType>>8), byte(protocolType)
+	copy(p[greHeaderLen:], payload)
+	return encapsulate(outer, protocolGRE, p)
+}
+
+// DecapsulateGRE parses b as a GRE encapsulated IPv4 datagram in wire
+// format and returns the outer IPv4 header, the protocol type and the
+// payload, which shares the underlying array with b.  The optional
+// fields of the GRE header described in RFC 2784 and RFC 2890 are
+// skipped; the checksum is not verified.  It returns an error for the
+// GRE versions other than 0.
+func DecapsulateGRE(b []byte) (outer *Header, protocolType uint16, payload []byte, err error) {
+	if outer, payload, err = decapsulate(b, protocolGRE); err != nil {
+		return nil, 0, nil, err
+	}
+	if len(payload) < greHeaderLen {
+		return nil, 0, nil, errBufferTooShort
+	}
+	if payload[1]&0x07 != 0 { // version
+		return nil, 0, nil, errNotEncapsulated
+	}
+	l := greHeaderLen
+	for _, f := range []byte{0x80, 0x20, 0x10} { // checksum, key and sequence number present
+		if payload[0]&f != 0 {
+			l += 4
+		}
+	}
+	if len(payload) < l {
+		return nil, 0, nil, errBufferTooShort
+	}
+	return outer, uint16(payload[2])<<8 | uint16(payload[3]), payload[l:], nil
+}
+
+// encapsulate prepends the outer IPv4 header carrying the protocol
+// proto to p.
+func encapsulate(outer *Header, proto int, p []byte) ([]byte, error) {
+	outer.Len = HeaderLen + len(outer.Options)
+	if outer.Len+len(p) > 0xffff {
+		return nil, errInvalidTotalLen
+	}
+	outer.TotalLen = outer.Len + len(p)
+	outer.Protocol = proto
+	outer.Checksum = 0
+	b, err := outer.marshal(false)
+	if err != nil {
+		return nil, err
+	}
+	outer.Checksum = int(checksum(b))
+	b[posChecksum], b[posChecksum+1] = byte(outer.Checksum>>8), byte(outer.Checksum)
+	return append(b, p...), nil
+}
+
+// decapsulate parses b as an IPv4 datagram carrying the protocol
+// proto in wire format and returns its header and payload.
+func decapsulate(b []byte, proto int) (*Header, []byte, error) {
+	h, err := parseHeader(b, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.Protocol != proto {
+		return nil, nil, errNotEncapsulated
+	}
+	if h.TotalLen < h.Len || h.TotalLen > len(b) {
+		return nil, nil, errInvalidTotalLen
+	}
+	return h, b[h.Len:h.TotalLen], nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+var (
+	innerDatagram = []byte{
+		0x45, 0x00, 0x00, 0x1b, 0xca, 0xfe, 0x00, 0x00,
+		0x40, 0x11, 0x00, 0x00, 10, 0, 0, 1,
+		10, 0, 0, 2,
+		'H', 'E', 'L', 'L', 'O', '-', 'R',
+	}
+	ipipDatagram = []byte{
+		0x45, 0x00, 0x00, 0x2f, 0xbe, 0xef, 0x40, 0x00,
+		0x05, 0x04, 0xca, 0xa5, 192, 0, 2, 1,
+		198, 51, 100, 1,
+	}
+)
+
+func TestEncapsulateIPIP(t *testing.T) {
+	outer := &ipv4.Header{
+		Version: ipv4.Version,
+		ID:      0xbeef,
+		Flags:   ipv4.DontFragment,
+		TTL:     5,
+		Src:     net.IPv4(192, 0, 2, 1),
+		Dst:     net.IPv4(198, 51, 100, 1),
+	}
+	b, err := ipv4.EncapsulateIPIP(outer, innerDatagram)
+	if err != nil {
+		t.Fatalf("ipv4.EncapsulateIPIP failed: %v", err)
+	}
+	wb := append(append([]byte{}, ipipDatagram...), innerDatagram...)
+	if !bytes.Equal(b, wb) {
+		t.Fatalf("got %#v; expected %#v", b, wb)
+	}
+	if outer.Protocol != 4 || outer.TotalLen != len(wb) || outer.Checksum != 0xcaa5 {
+		t.Fatalf("got %v; expected updated outer header", outer)
+	}
+
+	h, inner, err := ipv4.DecapsulateIPIP(b)
+	if err != nil {
+		t.Fatalf("ipv4.DecapsulateIPIP failed: %v", err)
+	}
+	if !bytes.Equal(inner, innerDatagram) {
+		t.Fatalf("got %#v; expected %#v", inner, innerDatagram)
+	}
+	if h.TTL != 5 || !h.Src.Equal(outer.Src) || !h.Dst.Equal(outer.Dst) {
+		t.Fatalf("got %v; expected %v", h, outer)
+	}
+
+	if _, err := ipv4.EncapsulateIPIP(outer, innerDatagram[:ipv4.HeaderLen-1]); err == nil {
+		t.Fatal("ipv4.EncapsulateIPIP succeeded with short inner datagram")
+	}
+	b[9] = 17 // not ip-in-ip
+	if _, _, err := ipv4.DecapsulateIPIP(b); err == nil {
+		t.Fatal("ipv4.DecapsulateIPIP succeeded with UDP datagram")
+	}
+}
+
+var greDatagram = []byte{
+	0x45, 0x00, 0x00, 0x33, 0x00, 0x00, 0x40, 0x00,
+	0xff, 0x2f, 0x00, 0x00, 192, 0, 2, 1,
+	198, 51, 100, 1,
+	0x00, 0x00, 0x08, 0x00, // gre header, ipv4
+}
+
+func TestEncapsulateGRE(t *testing.T) {
+	outer := &ipv4.Header{
+		Version: ipv4.Version,
+		Flags:   ipv4.DontFragment,
+		TTL:     255,
+		Src:     net.IPv4(192, 0, 2, 1),
+		Dst:     net.IPv4(198, 51, 100, 1),
+	}
+	b, err := ipv4.EncapsulateGRE(outer, 0x0800, innerDatagram)
+	if err != nil {
+		t.Fatalf("ipv4.EncapsulateGRE failed: %v", err)
+	}
+	wb := append(append([]byte{}, greDatagram...), innerDatagram...)
+	wb[10], wb[11] = byte(outer.Checksum>>8), byte(outer.Checksum)
+	if !bytes.Equal(b, wb) {
+		t.Fatalf("got %#v; expected %#v", b, wb)
+	}
+	h, err := ipv4.ParseHeader(b)
+	if err != nil {
+		t.Fatalf("ipv4.ParseHeader failed: %v", err)
+	}
+	if h.Protocol != 47 || h.TTL != 255 {
+		t.Fatalf("got %v; expected GRE header", h)
+	}
+
+	h, typ, payload, err := ipv4.DecapsulateGRE(b)
+	if err != nil {
+		t.Fatalf("ipv4.DecapsulateGRE failed: %v", err)
+	}
+	if typ != 0x0800 || !bytes.Equal(payload, innerDatagram) || !h.Dst.Equal(outer.Dst) {
+		t.Fatalf("got %v, %#04x, %#v; expected %v, 0x0800, %#v", h, typ, payload, outer, innerDatagram)
+	}
+
+	// Key present, see RFC 2890.
+	kb := append(append(append([]byte{}, greDatagram...), 0, 0, 0, 1), innerDatagram...)
+	kb[3] += 4
+	kb[20] = 0x20
+	if _, typ, payload, err := ipv4.DecapsulateGRE(kb); err != nil || typ != 0x0800 || !bytes.Equal(payload, innerDatagram) {
+		t.Fatalf("got %#04x, %#v, %v; expected 0x0800, %#v, <nil>", typ, payload, err, innerDatagram)
+	}
+	b[21] = 1 // version 1
+	if _, _, _, err := ipv4.DecapsulateGRE(b); err == nil {
+		t.Fatal("ipv4.DecapsulateGRE succeeded with GRE version 1")
+	}
+}
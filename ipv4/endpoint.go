@@ -6,8 +6,11 @@ package ipv4
 
 import (
 	"net"
+	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/net/context"
 )
 
 // A Conn represents a network endpoint that uses the IPv4 transport.
@@ -43,15 +46,27 @@ type PacketConn struct {
 
 type dgramOpt struct {
 	net.PacketConn
+	groups memberships // groups joined through JoinGroup
 }
 
 func (c *dgramOpt) ok() bool { return c != nil && c.PacketConn != nil }
 
 // SetControlMessage sets the per packet IP-level socket options.
+// Only the options specified by cf are turned on or off; the others
+// are left untouched.
+//
+// FlagRawHeader is supported only when the underlying transport is
+// *net.IPConn, since the protocol stack doesn't pass the IPv4 header
+// to UDP endpoints.  It is implemented in the package and requires
+// no kernel support, though platforms that don't support the other
+// flags don't support it either.
 func (c *PacketConn) SetControlMessage(cf ControlFlags, on bool) error {
 	if !c.payloadHandler.ok() {
 		return syscall.EINVAL
 	}
+	if _, ok := c.payloadHandler.conn().(*net.IPConn); cf&FlagRawHeader != 0 && !ok {
+		return ErrNotSupported
+	}
 	fd, err := c.payloadHandler.sysfd()
 	if err != nil {
 		return err
@@ -59,6 +74,107 @@ func (c *PacketConn) SetControlMessage(cf ControlFlags, on bool) error {
 	return setControlMessage(fd, &c.payloadHandler.rawOpt, cf, on)
 }
 
+// SetControlMessage2 is like SetControlMessage but tries the options
+// specified by cf one by one, skipping the ones that are not
+// supported, and returns the options that actually took effect.  An
+// option took effect when it is turned on, or off when on is false,
+// after the call.  The returned error is non-nil only on a failure
+// other than lack of support, along with the options that took
+// effect before the failure.
+//
+// The options available on each platform are:
+//
+//	Linux: FlagTTL, FlagSrc, FlagDst, FlagInterface, FlagFragSize,
+//	       FlagTOS, FlagRawHeader, FlagIncomingCPU
+//	FreeBSD: FlagTTL, FlagDst, FlagInterface, FlagTOS, FlagRawHeader
+//	Darwin, DragonFly BSD, NetBSD, OpenBSD: FlagTTL, FlagDst,
+//	       FlagInterface, FlagRawHeader
+//	Windows and others: none
+//
+// FlagRawHeader is available only when the underlying transport is
+// *net.IPConn.
+func (c *PacketConn) SetControlMessage2(cf ControlFlags, on bool) (ControlFlags, error) {
+	if !c.payloadHandler.ok() {
+		return 0, syscall.EINVAL
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return 0, err
+	}
+	_, isIP := c.payloadHandler.conn().(*net.IPConn)
+	var done ControlFlags
+	for f := FlagTTL; f <= FlagIncomingCPU; f <<= 1 {
+		if cf&f == 0 || f == FlagRawHeader && !isIP {
+			continue
+		}
+		if err := setControlMessage(fd, &c.payloadHandler.rawOpt, f, on); err == ErrNotSupported {
+			continue
+		} else if err != nil {
+			return done, err
+		}
+		c.payloadHandler.rawOpt.RLock()
+		if c.payloadHandler.rawOpt.isset(f) == on {
+			done |= f
+		}
+		c.payloadHandler.rawOpt.RUnlock()
+	}
+	return done, nil
+}
+
+// SupportedControlFlags returns the per packet IP-level socket
+// options that SetControlMessage can turn on for the endpoint c on
+// the running platform, without changing any option of the endpoint.
+// The options are the ones listed in SetControlMessage2, and
+// FlagRawHeader is included only when the underlying transport is
+// *net.IPConn.
+func (c *PacketConn) SupportedControlFlags() ControlFlags {
+	if !c.payloadHandler.ok() {
+		return 0
+	}
+	cf := supportedControlFlags()
+	if _, ok := c.payloadHandler.conn().(*net.IPConn); !ok {
+		cf &^= FlagRawHeader
+	}
+	return cf
+}
+
+// SetRecvCPU sets whether ReadFrom reports the CPU on which the
+// protocol stack processed the received packet in the IncomingCPU
+// field of the control message.  It is a shorthand for
+// SetControlMessage(FlagIncomingCPU, on).
+//
+// It is currently supported only on Linux, and requires the
+// SO_INCOMING_CPU socket option, which appeared in Linux 3.19.  The
+// value is queried from the socket after each read and therefore
+// reflects the most recent packet processed for the endpoint, which
+// may not be the one just read when packets arrive on several CPUs.
+func (c *PacketConn) SetRecvCPU(on bool) error {
+	return c.SetControlMessage(FlagIncomingCPU, on)
+}
+
+// EnableControlMessage turns on the per packet IP-level socket
+// options specified by cf in addition to the ones already on.
+func (c *PacketConn) EnableControlMessage(cf ControlFlags) error {
+	return c.SetControlMessage(cf, true)
+}
+
+// DisableControlMessage turns off the per packet IP-level socket
+// options specified by cf.
+func (c *PacketConn) DisableControlMessage(cf ControlFlags) error {
+	return c.SetControlMessage(cf, false)
+}
+
+// ControlMessageFlags returns the per packet IP-level socket options
+// currently turned on.
+func (c *PacketConn) ControlMessageFlags() ControlFlags {
+	if !c.payloadHandler.ok() {
+		return 0
+	}
+	c.payloadHandler.rawOpt.RLock()
+	defer c.payloadHandler.rawOpt.RUnlock()
+	return c.payloadHandler.rawOpt.cflags
+}
+
 // SetDeadline sets the read and write deadlines associated with the
 // endpoint.
 func (c *PacketConn) SetDeadline(t time.Time) error {
@@ -86,6 +202,83 @@ func (c *PacketConn) SetWriteDeadline(t time.Time) error {
 	return c.payloadHandler.PacketConn.SetWriteDeadline(t)
 }
 
+// SetDeadlineContext ties the read and write deadlines associated
+// with the endpoint to ctx.  It sets the deadlines to the deadline of
+// ctx, if any, and sets them to the past when ctx is done, which
+// makes pending and future reads and writes fail with a timeout
+// error.  A later call to SetDeadline overrides the deadlines until
+// ctx is done.
+//
+// A goroutine watches ctx until it is done or the returned stop
+// function is called, whichever comes first.  Calling stop unties the
+// deadlines from ctx; once it returns, the deadlines are no longer
+// changed on behalf of ctx, although they may have been set to the
+// past already if ctx was done before stop was called.  It should be
+// called once the endpoint is no longer used with ctx, for a ctx that
+// may never be done.  The stop function may be called more than once.
+func (c *PacketConn) SetDeadlineContext(ctx context.Context) (stop func(), err error) {
+	if !c.payloadHandler.ok() {
+		return nil, syscall.EINVAL
+	}
+	if t, ok := ctx.Deadline(); ok {
+		if err := c.payloadHandler.PacketConn.SetDeadline(t); err != nil {
+			return nil, err
+		}
+	}
+	w := watchContext(ctx, func() { c.payloadHandler.PacketConn.SetDeadline(time.Unix(1, 0)) })
+	return func() { w.stop() }, nil
+}
+
+// A ctxWatcher runs a function when a context is done, unless it is
+// stopped first.
+type ctxWatcher struct {
+	mu      sync.Mutex
+	stopped bool
+	fired   bool          // whether the function has run
+	done    chan struct{} // closed on stop
+	exited  chan struct{} // closed when the goroutine returns
+}
+
+// watchContext starts a goroutine that calls expire when ctx is done,
+// which is typically used to set a deadline to the past.
+func watchContext(ctx context.Context, expire func()) *ctxWatcher {
+	w := &ctxWatcher{done: make(chan struct{}), exited: make(chan struct{})}
+	if ctx.Done() == nil {
+		w.stopped = true
+		close(w.done)
+		close(w.exited)
+		return w
+	}
+	go func() {
+		defer close(w.exited)
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			if !w.stopped {
+				expire()
+				w.fired = true
+			}
+			w.mu.Unlock()
+		case <-w.done:
+		}
+	}()
+	return w
+}
+
+// stop stops the watcher and waits for its goroutine to return.  It
+// reports whether expire has been called; expire is never called once
+// stop returns.  It may be called more than once.
+func (w *ctxWatcher) stop() bool {
+	w.mu.Lock()
+	if !w.stopped {
+		w.stopped = true
+		close(w.done)
+	}
+	w.mu.Unlock()
+	<-w.exited
+	return w.fired
+}
+
 // Close closes the endpoint.
 func (c *PacketConn) Close() error {
 	if !c.payloadHandler.ok() {
@@ -94,14 +287,88 @@ func (c *PacketConn) Close() error {
 	return c.payloadHandler.PacketConn.Close()
 }
 
+// LocalUDPAddr returns the local network address of the endpoint as
+// *net.UDPAddr.  It returns ErrInvalidConnType when the underlying
+// transport is not *net.UDPConn.
+func (c *PacketConn) LocalUDPAddr() (*net.UDPAddr, error) {
+	if !c.payloadHandler.ok() {
+		return nil, syscall.EINVAL
+	}
+	uc, ok := c.payloadHandler.conn().(*net.UDPConn)
+	if !ok {
+		return nil, ErrInvalidConnType
+	}
+	a, ok := uc.LocalAddr().(*net.UDPAddr)
+	if !ok || a == nil {
+		return nil, ErrMissingAddress
+	}
+	return a, nil
+}
+
+// CloseGraceful leaves all the groups joined through JoinGroup or
+// JoinGroupAddr and not left yet, and then closes the endpoint.
+// Leaving the groups explicitly makes the protocol stack send IGMP
+// leave messages promptly, instead of at its own convenience when
+// the socket is released.
+//
+// It is best-effort: failures on leaving a group are ignored, and it
+// may block briefly for each group while the protocol stack
+// processes the membership change.
+func (c *PacketConn) CloseGraceful() error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	c.dgramOpt.leaveAllGroups()
+	return c.payloadHandler.PacketConn.Close()
+}
+
 // NewPacketConn returns a new PacketConn using c as its underlying
-// transport.
+// transport.  The c must be *net.UDPConn or *net.IPConn; the other
+// types are rejected with ErrInvalidConnType by the datagram based
+// I/O methods.  See NewIPPacketConn for using *net.IPConn.
 func NewPacketConn(c net.PacketConn) *PacketConn {
+	t := newTransport(c)
 	return &PacketConn{
-		genericOpt:     genericOpt{Conn: c.(net.Conn)},
-		dgramOpt:       dgramOpt{PacketConn: c},
-		payloadHandler: payloadHandler{PacketConn: c},
+		genericOpt:     genericOpt{Conn: t},
+		dgramOpt:       dgramOpt{PacketConn: t},
+		payloadHandler: payloadHandler{PacketConn: t},
+	}
+}
+
+// NewIPPacketConn is like NewPacketConn but takes an IPv4 raw
+// endpoint c, such as the one created by net.ListenIP with "ip4:47"
+// for GRE, and validates it.  It allows the datagrams of any
+// protocol to be sent and received with the datagram semantics: the
+// protocol stack builds the IPv4 header on transmission, and ReadFrom
+// strips the header on reception, returning the protocol payload
+// only.  The c may be unconnected, in which case the destination
+// address given to WriteTo must be *net.IPAddr.
+//
+// It returns ErrInvalidConnType when c is not bound to an IPv4
+// address, or has the IP_HDRINCL option enabled, as the endpoints
+// wrapped by NewRawConn have, which makes the protocol stack expect
+// the header from the application.
+//
+// The control messages available on the platform apply to the
+// endpoint as they do to UDP endpoints, and FlagRawHeader
+// additionally passes the stripped IPv4 header in the Header field
+// of the control message.
+func NewIPPacketConn(c *net.IPConn) (*PacketConn, error) {
+	if c == nil {
+		return nil, syscall.EINVAL
+	}
+	if a, ok := c.LocalAddr().(*net.IPAddr); !ok || a != nil && a.IP != nil && a.IP.To4() == nil {
+		return nil, ErrInvalidConnType
+	}
+	p := NewPacketConn(c)
+	fd, err := p.payloadHandler.sysfd()
+	if err != nil {
+		return nil, err
+	}
+	if on, err := getInt(fd, &sockOpts[ssoHeaderPrepend]); err == nil && on != 0 {
+		return nil, ErrInvalidConnType
 	}
+	return p, nil
 }
 
 // A RawConn represents a packet network endpoint that uses the IPv4
@@ -116,6 +383,8 @@ type RawConn struct {
 }
 
 // SetControlMessage sets the per packet IP-level socket options.
+// Only the options specified by cf are turned on or off; the others
+// are left untouched.
 func (c *RawConn) SetControlMessage(cf ControlFlags, on bool) error {
 	if !c.packetHandler.ok() {
 		return syscall.EINVAL
@@ -127,6 +396,74 @@ func (c *RawConn) SetControlMessage(cf ControlFlags, on bool) error {
 	return setControlMessage(fd, &c.packetHandler.rawOpt, cf, on)
 }
 
+// EnableControlMessage turns on the per packet IP-level socket
+// options specified by cf in addition to the ones already on.
+func (c *RawConn) EnableControlMessage(cf ControlFlags) error {
+	return c.SetControlMessage(cf, true)
+}
+
+// DisableControlMessage turns off the per packet IP-level socket
+// options specified by cf.
+func (c *RawConn) DisableControlMessage(cf ControlFlags) error {
+	return c.SetControlMessage(cf, false)
+}
+
+// ControlMessageFlags returns the per packet IP-level socket options
+// currently turned on.
+func (c *RawConn) ControlMessageFlags() ControlFlags {
+	if !c.packetHandler.ok() {
+		return 0
+	}
+	c.packetHandler.rawOpt.RLock()
+	defer c.packetHandler.rawOpt.RUnlock()
+	return c.packetHandler.rawOpt.cflags
+}
+
+// SetVerifyChecksum sets whether ReadFrom verifies the header
+// checksum of each received datagram.  When enabled, ReadFrom returns
+// ErrInvalidChecksum for a datagram with a bad checksum.  It is
+// disabled by default, which allows the application to capture
+// datagrams whose checksum is left unverified or zero due to
+// checksum offloading.
+// Verification is supported only on platforms that pass the header
+// to the application without modification, currently Linux and
+// OpenBSD.
+func (c *RawConn) SetVerifyChecksum(on bool) error {
+	if !c.packetHandler.ok() {
+		return syscall.EINVAL
+	}
+	if on && !supportsNewIPInput {
+		return ErrNotSupported
+	}
+	c.packetHandler.rawOpt.Lock()
+	c.packetHandler.verify = on
+	c.packetHandler.rawOpt.Unlock()
+	return nil
+}
+
+// SetChecksumOffload requests the protocol stack to fill the
+// transport checksum, located at offset octets from the beginning of
+// the payload, of outgoing datagrams carrying the transport protocol
+// proto.
+//
+// No platform currently supports this on IPv4 raw sockets, and it
+// always returns ErrNotSupported for valid arguments.  The
+// IPV6_CHECKSUM option is defined only for IPv6 raw sockets, and
+// SO_NO_CHECK on Linux merely disables the UDP checksum of UDP
+// sockets.  Since the endpoint has the IP_HDRINCL option enabled, the
+// datagram is transmitted as written by the application, and the
+// application needs to compute the transport checksum itself, for
+// example by using UpdateTransportChecksum.
+func (c *RawConn) SetChecksumOffload(proto, offset int) error {
+	if !c.packetHandler.ok() {
+		return syscall.EINVAL
+	}
+	if proto < 0 || proto > 0xff || offset < 0 || offset&1 != 0 {
+		return syscall.EINVAL
+	}
+	return ErrNotSupported
+}
+
 // SetDeadline sets the read and write deadlines associated with the
 // endpoint.
 func (c *RawConn) SetDeadline(t time.Time) error {
@@ -154,6 +491,40 @@ func (c *RawConn) SetWriteDeadline(t time.Time) error {
 	return c.packetHandler.c.SetWriteDeadline(t)
 }
 
+// LocalIPAddr returns the local network address of the endpoint as
+// *net.IPAddr.
+func (c *RawConn) LocalIPAddr() (*net.IPAddr, error) {
+	if !c.packetHandler.ok() {
+		return nil, syscall.EINVAL
+	}
+	a, ok := c.packetHandler.c.LocalAddr().(*net.IPAddr)
+	if !ok || a == nil {
+		return nil, ErrMissingAddress
+	}
+	return a, nil
+}
+
+// Protocol returns the IP protocol number with which the underlying
+// socket of the endpoint was opened, such as iana.ProtocolICMP for an
+// endpoint created from net.ListenPacket with "ip4:icmp", which is
+// suitable for parsing the payloads read from the endpoint.  It
+// returns -1 when the protocol number is unknown.
+// Currently only Linux supports this.
+func (c *RawConn) Protocol() int {
+	if !c.packetHandler.ok() {
+		return -1
+	}
+	fd, err := c.packetHandler.sysfd()
+	if err != nil {
+		return -1
+	}
+	proto, err := getInt(fd, &sockOpts[ssoProtocol])
+	if err != nil {
+		return -1
+	}
+	return proto
+}
+
 // Close closes the endpoint.
 func (c *RawConn) Close() error {
 	if !c.packetHandler.ok() {
@@ -0,0 +1,32 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sysError returns err, an error returned by the net package, with
+// a bare syscall.Errno in the Err field of *net.OpError wrapped in
+// *os.SyscallError for the system call name.  Older versions of the
+// standard library don't wrap the errno, which this hides from the
+// callers of PacketConn and RawConn.
+func sysError(name string, err error) error {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		return err
+	}
+	errno, ok := oe.Err.(syscall.Errno)
+	if !ok {
+		return err
+	}
+	noe := *oe
+	noe.Err = os.NewSyscallError(name, errno)
+	return &noe
+}
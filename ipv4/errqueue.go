@@ -0,0 +1,24 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import "net"
+
+// Origins of extended errors
+const (
+	ExtendedErrOriginLocal = 1 // error generated by the local node
+	ExtendedErrOriginICMP  = 2 // error reported by ICMP
+)
+
+// An ExtendedErr represents an extended reliable error message
+// retrieved from the error queue of the endpoint.
+type ExtendedErr struct {
+	Err      error  // error number, syscall.Errno
+	Origin   int    // origin of the error
+	Type     int    // ICMP type when Origin is ExtendedErrOriginICMP
+	Code     int    // ICMP code when Origin is ExtendedErrOriginICMP
+	Info     int    // additional information, e.g. the next-hop MTU
+	Offender net.IP // node that reported the error, if any
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+// SetReceiveErrors sets whether extended reliable error messages,
+// such as ICMP errors and local transmission errors, are queued on
+// the error queue of the endpoint.  The queued messages can be
+// retrieved by ReadErrQueueOnly.
+// Currently only Linux supports this.
+func (c *PacketConn) SetReceiveErrors(on bool) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInt(fd, &sockOpts[ssoReceiveErr], boolint(on))
+}
+
+// ReadErrQueueOnly reads an extended error message from the error
+// queue of the endpoint without reading any payload.  It returns the
+// extended error and the destination address of the datagram that
+// caused the error.
+//
+// ReadErrQueueOnly blocks until an extended error message is queued.
+// The wait is made on the runtime network poller and is subject to
+// the read deadline, which it reports as a timeout error.  Datagrams
+// arriving meanwhile are left for ReadFrom.  A queued error also
+// makes a blocking ReadFrom return with the error, so the application
+// can call ReadFrom as usual and drain the error queue once ReadFrom
+// fails.
+// Currently only Linux supports this.
+func (c *PacketConn) ReadErrQueueOnly() (*ExtendedErr, net.Addr, error) {
+	if !c.payloadHandler.ok() {
+		return nil, nil, syscall.EINVAL
+	}
+	pc := c.payloadHandler.conn()
+	rc, err := rawConn(pc)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The per packet options turned on by SetControlMessage are
+	// passed along with the extended error.
+	oob := newControlMessage(&c.payloadHandler.rawOpt)
+	oob = append(oob, make([]byte, syscall.CmsgSpace(sysSizeofSockExtendedErr+syscall.SizeofSockaddrInet4))...)
+	var oobn int
+	var from syscall.Sockaddr
+	var serr error
+	err = rc.Read(func(fd uintptr) bool {
+		_, oobn, _, from, serr = syscall.Recvmsg(int(fd), nil, oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+		return serr != syscall.EAGAIN
+	})
+	if err != nil {
+		return nil, nil, rawOpError("read", err)
+	}
+	if serr != nil {
+		return nil, nil, &net.OpError{Op: "read", Net: pc.LocalAddr().Network(), Err: os.NewSyscallError("recvmsg", serr)}
+	}
+	ee, err := parseExtendedErr(oob[:oobn])
+	if err != nil {
+		return nil, nil, err
+	}
+	var dst net.Addr
+	if sa, ok := from.(*syscall.SockaddrInet4); ok {
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, sa.Addr[:])
+		switch pc.(type) {
+		case *net.UDPConn:
+			dst = &net.UDPAddr{IP: ip, Port: sa.Port}
+		case *net.IPConn:
+			dst = &net.IPAddr{IP: ip}
+		}
+	}
+	return ee, dst, nil
+}
+
+var errNoExtendedErr = errors.New("no extended error")
+
+func parseExtendedErr(b []byte) (*ExtendedErr, error) {
+	cmsgs, err := syscall.ParseSocketControlMessage(b)
+	if err != nil {
+		return nil, os.NewSyscallError("parse socket control message", err)
+	}
+	for _, m := range cmsgs {
+		if m.Header.Level != iana.ProtocolIP || m.Header.Type != sysIP_RECVERR || len(m.Data) < sysSizeofSockExtendedErr {
+			continue
+		}
+		se := (*sysSockExtendedErr)(unsafe.Pointer(&m.Data[0]))
+		ee := &ExtendedErr{
+			Err:    syscall.Errno(se.Errno),
+			Origin: int(se.Origin),
+			Type:   int(se.Type),
+			Code:   int(se.Code),
+			Info:   int(se.Info),
+		}
+		if sa := m.Data[sysSizeofSockExtendedErr:]; len(sa) >= syscall.SizeofSockaddrInet4 {
+			if *(*uint16)(unsafe.Pointer(&sa[0])) == syscall.AF_INET {
+				ee.Offender = make(net.IP, net.IPv4len)
+				copy(ee.Offender, sa[4:8])
+			}
+		}
+		return ee, nil
+	}
+	return nil, errNoExtendedErr
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+func TestParseExtendedErr(t *testing.T) {
+	l := sysSizeofSockExtendedErr + syscall.SizeofSockaddrInet4
+	b := make([]byte, syscall.CmsgSpace(l))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolIP
+	m.Type = sysIP_RECVERR
+	m.SetLen(syscall.CmsgLen(l))
+	data := b[syscall.CmsgLen(0):]
+	se := (*sysSockExtendedErr)(unsafe.Pointer(&data[0]))
+	se.Errno = uint32(syscall.EMSGSIZE)
+	se.Origin = ExtendedErrOriginICMP
+	se.Type = uint8(ICMPTypeDestinationUnreachable)
+	se.Code = 4
+	se.Info = 1280
+	sa := data[sysSizeofSockExtendedErr:]
+	*(*uint16)(unsafe.Pointer(&sa[0])) = syscall.AF_INET
+	copy(sa[4:8], net.IP{192, 0, 2, 1})
+
+	ee, err := parseExtendedErr(b)
+	if err != nil {
+		t.Fatalf("parseExtendedErr failed: %v", err)
+	}
+	if ee.Err != syscall.EMSGSIZE || ee.Origin != ExtendedErrOriginICMP || ee.Type != int(ICMPTypeDestinationUnreachable) || ee.Code != 4 || ee.Info != 1280 {
+		t.Fatalf("got %+v; expected EMSGSIZE from ICMP destination unreachable with mtu 1280", ee)
+	}
+	if !ee.Offender.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Fatalf("got %v; expected %v", ee.Offender, net.IPv4(192, 0, 2, 1))
+	}
+
+	if _, err := parseExtendedErr(nil); err != errNoExtendedErr {
+		t.Fatalf("got %v; expected %v", err, errNoExtendedErr)
+	}
+}
+
+func TestPacketConnReadErrQueueOnlyWithControlMessage(t *testing.T) {
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := NewPacketConn(c)
+	if err := p.SetReceiveErrors(true); err != nil {
+		t.Fatalf("PacketConn.SetReceiveErrors failed: %v", err)
+	}
+	if err := p.SetControlMessage(FlagTTL|FlagDst|FlagInterface, true); err != nil {
+		t.Fatalf("PacketConn.SetControlMessage failed: %v", err)
+	}
+
+	// A closed port makes the protocol stack queue an ICMP port
+	// unreachable error along with the per packet options.
+	cc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	dst := cc.LocalAddr()
+	cc.Close()
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, dst); err != nil {
+		t.Fatalf("PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("PacketConn.SetReadDeadline failed: %v", err)
+	}
+	ee, _, err := p.ReadErrQueueOnly()
+	if err != nil {
+		t.Fatalf("PacketConn.ReadErrQueueOnly failed: %v", err)
+	}
+	if ee.Err != syscall.ECONNREFUSED {
+		t.Fatalf("got %v; expected %v", ee.Err, syscall.ECONNREFUSED)
+	}
+}
+
+func TestPacketConnReadErrQueueOnlyDeadline(t *testing.T) {
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := NewPacketConn(c)
+	if err := p.SetReceiveErrors(true); err != nil {
+		t.Fatalf("PacketConn.SetReceiveErrors failed: %v", err)
+	}
+
+	// A datagram doesn't end the wait for an extended error.
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("PacketConn.WriteTo failed: %v", err)
+	}
+	const timeout = 100 * time.Millisecond
+	if err := p.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		t.Fatalf("PacketConn.SetReadDeadline failed: %v", err)
+	}
+	start := time.Now()
+	_, _, err = p.ReadErrQueueOnly()
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+	if d := time.Since(start); d < timeout/2 {
+		t.Fatalf("returned after %v; expected to block until the deadline", d)
+	}
+	if err := p.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("PacketConn.SetReadDeadline failed: %v", err)
+	}
+	if _, _, _, err := p.ReadFrom(make([]byte, 128)); err != nil {
+		t.Fatalf("PacketConn.ReadFrom failed: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+import "net"
+
+// SetReceiveErrors sets whether extended reliable error messages,
+// such as ICMP errors and local transmission errors, are queued on
+// the error queue of the endpoint.
+// Currently only Linux supports this.
+func (c *PacketConn) SetReceiveErrors(on bool) error {
+	return ErrNotSupported
+}
+
+// ReadErrQueueOnly reads an extended error message from the error
+// queue of the endpoint without reading any payload.
+// Currently only Linux supports this.
+func (c *PacketConn) ReadErrQueueOnly() (*ExtendedErr, net.Addr, error) {
+	return nil, nil, ErrNotSupported
+}
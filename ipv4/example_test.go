@@ -7,6 +7,8 @@ package ipv4_test
 import (
 	"log"
 	"net"
+	"os"
+	"time"
 
 	"golang.org/x/net/internal/iana"
 	"golang.org/x/net/ipv4"
@@ -114,6 +116,51 @@ func ExampleMulticastUDPListener() {
 	}
 }
 
+func Example_ecnPathProbe() {
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	// Mark outgoing echo requests as ECN-capable and watch the
+	// ECN codepoint of echo replies, which reveals whether the
+	// path preserves or bleaches the marks.
+	err = p.SetTOS(iana.DiffServCS0 | iana.ECNTransport0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = p.SetControlMessage(ipv4.FlagTOS, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dst := &net.IPAddr{IP: net.IPv4(192, 0, 2, 1)}
+	replyECN := make(map[int]int) // ECN codepoint to number of replies
+	b := make([]byte, 1500)
+	for seq := 0; seq < 8; seq++ {
+		wb, err := ipv4.NewEchoRequest(os.Getpid()&0xffff, seq, []byte("HELLO-R-U-THERE"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = p.WriteTo(wb, nil, dst)
+		if err != nil {
+			log.Fatal(err)
+		}
+		p.SetReadDeadline(time.Now().Add(time.Second))
+		n, cm, _, err := p.ReadFrom(b)
+		if err != nil {
+			continue // timed out, try next
+		}
+		if _, _, _, err := ipv4.ParseEchoReply(b[:n]); err != nil {
+			continue
+		}
+		replyECN[cm.ReceivedECN()]++
+	}
+	log.Println(replyECN)
+}
+
 type OSPFHeader struct {
 	Version  byte
 	Type     byte
@@ -0,0 +1,120 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"syscall"
+)
+
+var errFragmentationNeeded = errors.New("fragmentation needed and don't fragment flag set")
+
+// WriteToFragmented is like WriteTo but splits the datagram into
+// fragments that fit in mtu octets when the IPv4 header h and the
+// payload p don't, and writes the fragments in order as described in
+// RFC 791.  The TotalLen, FragOff and Flags fields of h are computed
+// for each fragment and the Checksum field is cleared so that the
+// platform calculates it; h itself is left unmodified.  Only the
+// options having the copied flag set are carried by the fragments
+// other than the first.  Any transport checksum must be calculated
+// over the whole payload beforehand.
+//
+// When h has DontFragment set, it returns an error instead of
+// fragmenting the datagram.  The mtu must be at least 68 octets, the
+// minimum required by RFC 791, and the options of h must not exceed
+// 40 octets.
+func (c *packetHandler) WriteToFragmented(h *Header, p []byte, mtu int, cm *ControlMessage) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	hs, ps, err := fragment(h, p, mtu)
+	if err != nil {
+		return err
+	}
+	for i := range hs {
+		if _, err := c.WriteToN(hs[i], ps[i], cm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fragment splits the datagram consisting of the header h and the
+// payload p into the fragments that fit in mtu octets, and returns
+// their headers and payloads.
+func fragment(h *Header, p []byte, mtu int) ([]*Header, [][]byte, error) {
+	if h == nil {
+		return nil, nil, ErrMissingHeader
+	}
+	if mtu < 68 {
+		return nil, nil, syscall.EINVAL
+	}
+	if len(h.Options) > maxHeaderLen-HeaderLen {
+		return nil, nil, errInvalidOption
+	}
+	hdrlen := HeaderLen + len(h.Options)
+	if hdrlen+len(p) <= mtu {
+		fh := *h
+		fh.Len, fh.TotalLen = hdrlen, hdrlen+len(p)
+		fh.Checksum = 0
+		return []*Header{&fh}, [][]byte{p}, nil
+	}
+	if h.Flags&DontFragment != 0 {
+		return nil, nil, errFragmentationNeeded
+	}
+	var hs []*Header
+	var ps [][]byte
+	opts, off := h.Options, h.FragOff
+	for len(p) > 0 {
+		fh := *h
+		fh.Options = opts
+		fh.Len = HeaderLen + len(opts)
+		fh.Checksum = 0
+		n := len(p)
+		if fh.Len+n > mtu {
+			n = (mtu - fh.Len) &^ 7
+			fh.Flags |= MoreFragments
+		}
+		if n == 0 {
+			return nil, nil, errInvalidOption
+		}
+		if off > 0x1fff {
+			return nil, nil, errInvalidFragOff
+		}
+		fh.FragOff = off
+		fh.TotalLen = fh.Len + n
+		hs = append(hs, &fh)
+		ps = append(ps, p[:n])
+		p, off = p[n:], off+n/8
+		opts = copiedOptions(h.Options)
+	}
+	return hs, ps, nil
+}
+
+// copiedOptions returns the options in b that have the copied flag
+// set, padded to a multiple of 4 octets.
+func copiedOptions(b []byte) []byte {
+	var opts []byte
+	for len(b) > 0 {
+		if b[0] == 0 { // end of option list
+			break
+		}
+		if b[0] == 1 { // no operation
+			b = b[1:]
+			continue
+		}
+		if len(b) < 2 || int(b[1]) < 2 || int(b[1]) > len(b) {
+			break
+		}
+		if b[0]&0x80 != 0 {
+			opts = append(opts, b[:b[1]]...)
+		}
+		b = b[b[1]:]
+	}
+	for len(opts)%4 != 0 {
+		opts = append(opts, 0)
+	}
+	return opts
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestFragment(t *testing.T) {
+	h := &Header{
+		Version:  Version,
+		Len:      HeaderLen + 8,
+		ID:       0xcafe,
+		TTL:      64,
+		Protocol: 17,
+		Src:      net.IPv4(192, 0, 2, 1),
+		Dst:      net.IPv4(198, 51, 100, 1),
+		// loose source route (copied), no operation, and
+		// record route (not copied)
+		Options: []byte{0x83, 3, 0, 1, 0x07, 3, 0, 0},
+	}
+	p := make([]byte, 300)
+	for i := range p {
+		p[i] = byte(i)
+	}
+	hs, ps, err := fragment(h, p, 128)
+	if err != nil {
+		t.Fatalf("fragment failed: %v", err)
+	}
+	if len(hs) != 3 {
+		t.Fatalf("got %v fragments; expected 3", len(hs))
+	}
+	off := 0
+	var q []byte
+	for i, fh := range hs {
+		if fh.TotalLen > 128 || fh.TotalLen != fh.Len+len(ps[i]) {
+			t.Errorf("#%v: got total length %v for %v octets of payload", i, fh.TotalLen, len(ps[i]))
+		}
+		if fh.FragOff*8 != off {
+			t.Errorf("#%v: got fragment offset %v; expected %v", i, fh.FragOff*8, off)
+		}
+		if more := fh.Flags&MoreFragments != 0; more != (i < len(hs)-1) {
+			t.Errorf("#%v: got more fragments flag %v", i, more)
+		}
+		if i > 0 && !bytes.Equal(fh.Options, []byte{0x83, 3, 0, 0}) {
+			t.Errorf("#%v: got options %#v", i, fh.Options)
+		}
+		if !fh.SameFragmentGroup(h) {
+			t.Errorf("#%v: %v isn't in the fragment group of %v", i, fh, h)
+		}
+		off += len(ps[i])
+		q = append(q, ps[i]...)
+	}
+	if !bytes.Equal(q, p) {
+		t.Fatal("reassembled payload differs from original")
+	}
+	if len(h.Options) != 8 || h.TotalLen != 0 || h.Flags != 0 {
+		t.Fatalf("original header is modified: %v", h)
+	}
+
+	h.Flags = DontFragment
+	if _, _, err := fragment(h, p, 128); err == nil {
+		t.Fatal("fragment succeeded with DontFragment")
+	}
+	if hs, _, err := fragment(h, p[:64], 128); err != nil || len(hs) != 1 || hs[0].TotalLen != HeaderLen+8+64 {
+		t.Fatalf("got %v, %v; expected a single fragment", hs, err)
+	}
+	if _, _, err := fragment(h, p, 67); err == nil {
+		t.Fatal("fragment succeeded with too small MTU")
+	}
+
+	// Options that leave no room for the payload of a fragment.
+	h.Flags = 0
+	h.Options = append([]byte{0x83, 48}, make([]byte, 46)...)
+	if _, _, err := fragment(h, p, 68); err == nil {
+		t.Fatal("fragment succeeded with too long options")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build gofuzz
+
+package ipv4
+
+// Fuzz is the entry point for go-fuzz.  It parses b as an IPv4
+// datagram and checks that a successfully parsed header survives a
+// round trip through Marshal and ParseHeader.
+func Fuzz(b []byte) int {
+	hs, _, err := slicePacket(b)
+	if err != nil {
+		return 0
+	}
+	h, err := ParseHeader(hs)
+	if err != nil {
+		return 0
+	}
+	if h.Len != len(hs) || len(h.Options) != h.Len-HeaderLen {
+		panic("inconsistent header length")
+	}
+	if _, err := h.Marshal(); err != nil && err != ErrMissingAddress {
+		panic(err)
+	}
+	return 1
+}
@@ -9,6 +9,8 @@ package ipv4
 import "syscall"
 
 // TOS returns the type-of-service field value for outgoing packets.
+// The value is queried from the protocol stack on each call and
+// reflects changes made to the socket by other means.
 func (c *genericOpt) TOS() (int, error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
@@ -34,6 +36,8 @@ func (c *genericOpt) SetTOS(tos int) error {
 }
 
 // TTL returns the time-to-live field value for outgoing packets.
+// The value is queried from the protocol stack on each call and
+// reflects changes made to the socket by other means.
 func (c *genericOpt) TTL() (int, error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
@@ -57,3 +61,65 @@ func (c *genericOpt) SetTTL(ttl int) error {
 	}
 	return setInt(fd, &sockOpts[ssoTTL], ttl)
 }
+
+// Priority returns the protocol-defined priority for outgoing
+// packets.
+func (c *genericOpt) Priority() (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return 0, err
+	}
+	return getInt(fd, &sockOpts[ssoPriority])
+}
+
+// SetPriority sets the protocol-defined priority for future outgoing
+// packets.
+// It is currently supported only on Linux, where it maps to the
+// SO_PRIORITY socket option used for queueing discipline selection.
+func (c *genericOpt) SetPriority(prio int) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInt(fd, &sockOpts[ssoPriority], prio)
+}
+
+// Mark returns the mark, also known as fwmark, attached to outgoing
+// packets.
+func (c *genericOpt) Mark() (uint32, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return 0, err
+	}
+	mark, err := getInt(fd, &sockOpts[ssoMark])
+	if err != nil {
+		return 0, err
+	}
+	return uint32(mark), nil
+}
+
+// SetMark sets the mark, also known as fwmark, attached to future
+// outgoing packets, which allows the packets to be matched by policy
+// routing rules and packet filters.  The ControlMessage for WriteTo
+// can override it per packet.
+// It is currently supported only on Linux, where it maps to the
+// SO_MARK socket option and requires the CAP_NET_ADMIN capability.
+func (c *genericOpt) SetMark(mark uint32) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	fd, err := c.sysfd()
+	if err != nil {
+		return err
+	}
+	return setInt(fd, &sockOpts[ssoMark], int(int32(mark)))
+}
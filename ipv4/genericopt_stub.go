@@ -7,17 +7,33 @@
 package ipv4
 
 func (c *genericOpt) TOS() (int, error) {
-	return 0, errOpNoSupport
+	return 0, ErrNotSupported
 }
 
 func (c *genericOpt) SetTOS(tos int) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
 
 func (c *genericOpt) TTL() (int, error) {
-	return 0, errOpNoSupport
+	return 0, ErrNotSupported
 }
 
 func (c *genericOpt) SetTTL(ttl int) error {
-	return errOpNoSupport
+	return ErrNotSupported
+}
+
+func (c *genericOpt) Priority() (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (c *genericOpt) SetPriority(prio int) error {
+	return ErrNotSupported
+}
+
+func (c *genericOpt) Mark() (uint32, error) {
+	return 0, ErrNotSupported
+}
+
+func (c *genericOpt) SetMark(mark uint32) error {
+	return ErrNotSupported
 }
@@ -0,0 +1,30 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+// SplitGRO splits buf, the coalesced UDP datagrams returned by
+// ReadFrom when SetGRO is turned on, into the individual datagrams of
+// segSize octets, the last of which may be shorter.  The segSize is
+// the GSOSize field of the control message; when it is not positive,
+// buf is assumed to hold a single datagram.  It returns nil when buf
+// is empty.
+//
+// The returned datagrams refer to buf, and each of them has its
+// capacity limited to its length so that appending to a datagram
+// never overwrites the next one.
+func SplitGRO(buf []byte, segSize int) [][]byte {
+	if len(buf) == 0 {
+		return nil
+	}
+	if segSize <= 0 || segSize >= len(buf) {
+		return [][]byte{buf[:len(buf):len(buf)]}
+	}
+	ps := make([][]byte, 0, (len(buf)+segSize-1)/segSize)
+	for len(buf) > segSize {
+		ps = append(ps, buf[:segSize:segSize])
+		buf = buf[segSize:]
+	}
+	return append(ps, buf[:len(buf):len(buf)])
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+var splitGROTests = []struct {
+	buf     string
+	segSize int
+	ps      []string
+}{
+	{"", 4, nil},
+	{"AAAABBBBCC", 4, []string{"AAAA", "BBBB", "CC"}},
+	{"AAAABBBB", 4, []string{"AAAA", "BBBB"}},
+	{"AAA", 4, []string{"AAA"}},
+	{"AAAA", 0, []string{"AAAA"}},
+	{"AAAA", -1, []string{"AAAA"}},
+	{"ABC", 1, []string{"A", "B", "C"}},
+}
+
+func TestSplitGRO(t *testing.T) {
+	for _, tt := range splitGROTests {
+		var ps []string
+		for _, p := range ipv4.SplitGRO([]byte(tt.buf), tt.segSize) {
+			if cap(p) != len(p) {
+				t.Errorf("got capacity %v for %q; expected %v", cap(p), p, len(p))
+			}
+			ps = append(ps, string(p))
+		}
+		if !reflect.DeepEqual(ps, tt.ps) {
+			t.Errorf("ipv4.SplitGRO(%q, %v) = %q; expected %q", tt.buf, tt.segSize, ps, tt.ps)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+// groControlMessageSpace is the space for the UDP_GRO control
+// message.
+var groControlMessageSpace = syscall.CmsgSpace(4)
+
+// SetGRO sets whether the protocol stack coalesces the received UDP
+// datagrams of the same flow into a single large datagram, which
+// ReadFrom returns along with the segment size in the GSOSize field
+// of the control message.  The buffer passed to ReadFrom must be
+// large enough to hold the coalesced datagrams, up to 65535 octets.
+// Currently only Linux 5.0 or above on UDP endpoints supports this.
+func (c *PacketConn) SetGRO(on bool) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	if _, ok := c.payloadHandler.conn().(*net.UDPConn); !ok {
+		return ErrInvalidConnType
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return err
+	}
+	if err := setInt(fd, &sockOpts[ssoUDPGRO], boolint(on)); err != nil {
+		return err
+	}
+	c.payloadHandler.rawOpt.Lock()
+	c.payloadHandler.rawOpt.gro = on
+	c.payloadHandler.rawOpt.Unlock()
+	return nil
+}
+
+// SetGSO sets the segment size for generic segmentation offload.
+// When size is positive, WriteTo passes it to the protocol stack,
+// which splits the payload into datagrams of size octets, the last
+// of which may be shorter, allowing the application to send many
+// datagrams with a single call.  A size of zero turns it off.
+// Currently only Linux 4.18 or above on UDP endpoints supports this.
+func (c *PacketConn) SetGSO(size int) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	if size < 0 || size > 0xffff {
+		return syscall.EINVAL
+	}
+	if _, ok := c.payloadHandler.conn().(*net.UDPConn); !ok {
+		return ErrInvalidConnType
+	}
+	c.payloadHandler.rawOpt.Lock()
+	c.payloadHandler.rawOpt.gsoSize = size
+	c.payloadHandler.rawOpt.Unlock()
+	return nil
+}
+
+// appendGSO appends the UDP_SEGMENT control message carrying size to
+// oob.
+func appendGSO(oob []byte, size int) []byte {
+	b := make([]byte, syscall.CmsgSpace(2))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = iana.ProtocolUDP
+	m.Type = sysUDP_SEGMENT
+	m.SetLen(syscall.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = uint16(size)
+	return append(oob, b...)
+}
+
+// parseGRO parses the UDP_GRO control message into cm.  It reports
+// whether the control message of level and typ is UDP_GRO.
+func parseGRO(cm *ControlMessage, level, typ int, b []byte) bool {
+	if level != iana.ProtocolUDP || typ != sysUDP_GRO {
+		return false
+	}
+	if len(b) >= 4 {
+		cm.GSOSize = int(*(*int32)(unsafe.Pointer(&b[:4][0])))
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/net/internal/iana"
+)
+
+func TestGSOControlMessage(t *testing.T) {
+	oob := appendGSO(nil, 1200)
+	cmsgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		t.Fatalf("syscall.ParseSocketControlMessage failed: %v", err)
+	}
+	if len(cmsgs) != 1 {
+		t.Fatalf("got %v control messages; expected 1", len(cmsgs))
+	}
+	m := cmsgs[0]
+	if m.Header.Level != iana.ProtocolUDP || m.Header.Type != sysUDP_SEGMENT {
+		t.Fatalf("got level=%v, type=%v; expected level=%v, type=%v", m.Header.Level, m.Header.Type, iana.ProtocolUDP, sysUDP_SEGMENT)
+	}
+	if v := *(*uint16)(unsafe.Pointer(&m.Data[0])); v != 1200 {
+		t.Fatalf("got %v; expected 1200", v)
+	}
+
+	var cm ControlMessage
+	b := make([]byte, 4)
+	*(*int32)(unsafe.Pointer(&b[0])) = 1200
+	if !parseGRO(&cm, iana.ProtocolUDP, sysUDP_GRO, b) {
+		t.Fatal("parseGRO didn't accept UDP_GRO")
+	}
+	if cm.GSOSize != 1200 {
+		t.Fatalf("got %v; expected 1200", cm.GSOSize)
+	}
+	if parseGRO(&cm, iana.ProtocolIP, sysUDP_GRO, b) {
+		t.Fatal("parseGRO accepted IP-level control message")
+	}
+}
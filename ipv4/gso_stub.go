@@ -0,0 +1,30 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+const groControlMessageSpace = 0
+
+// SetGRO sets whether the protocol stack coalesces the received UDP
+// datagrams of the same flow into a single large datagram.
+// Currently only Linux supports this.
+func (c *PacketConn) SetGRO(on bool) error {
+	return ErrNotSupported
+}
+
+// SetGSO sets the segment size for generic segmentation offload.
+// Currently only Linux supports this.
+func (c *PacketConn) SetGSO(size int) error {
+	return ErrNotSupported
+}
+
+func appendGSO(oob []byte, size int) []byte {
+	return oob
+}
+
+func parseGRO(cm *ControlMessage, level, typ int, b []byte) bool {
+	return false
+}
@@ -9,16 +9,21 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"sync"
 	"syscall"
 	"unsafe"
 )
 
 var (
-	errMissingAddress  = errors.New("missing address")
-	errMissingHeader   = errors.New("missing header")
-	errHeaderTooShort  = errors.New("header too short")
+	ErrMissingAddress  = errors.New("missing address")   // destination or group address is not specified
+	ErrMissingHeader   = errors.New("missing header")    // header is not specified
+	ErrHeaderTooShort  = errors.New("header too short")  // header is shorter than HeaderLen
+	ErrInvalidConnType = errors.New("invalid conn type") // underlying connection is not supported
+	ErrInvalidChecksum = errors.New("invalid checksum")  // header checksum verification failed
+	ErrNotMulticast    = errors.New("not multicast")     // group address is not a multicast address
 	errBufferTooShort  = errors.New("buffer too short")
-	errInvalidConnType = errors.New("invalid conn type")
+	errInvalidTotalLen = errors.New("invalid total length")
+	errInvalidFragOff  = errors.New("invalid fragment offset")
 )
 
 // References:
@@ -79,24 +84,154 @@ func (h *Header) String() string {
 	return fmt.Sprintf("ver: %v, hdrlen: %v, tos: %#x, totallen: %v, id: %#x, flags: %#x, fragoff: %#x, ttl: %v, proto: %v, cksum: %#x, src: %v, dst: %v", h.Version, h.Len, h.TOS, h.TotalLen, h.ID, h.Flags, h.FragOff, h.TTL, h.Protocol, h.Checksum, h.Src, h.Dst)
 }
 
+// Clone returns a deep copy of h, which shares no memory with h.
+// It is useful for handing a Header over to another goroutine while
+// the original is still in use or modified, such as a Header that
+// refers to address or option slices owned by the application.
+func (h *Header) Clone() *Header {
+	if h == nil {
+		return nil
+	}
+	nh := *h
+	nh.Src = cloneBytes(h.Src)
+	nh.Dst = cloneBytes(h.Dst)
+	nh.Options = cloneBytes(h.Options)
+	return &nh
+}
+
+// FragmentByteOffset returns the fragment offset of h in octets.
+// The FragOff field holds it in units of 8 octets.
+func (h *Header) FragmentByteOffset() int {
+	return h.FragOff * 8
+}
+
+// SetFragmentByteOffset sets the fragment offset of h to n octets.
+// The n must be a multiple of 8 and not greater than 65528, the
+// largest offset the fragment offset field can represent.
+func (h *Header) SetFragmentByteOffset(n int) error {
+	if n < 0 || n%8 != 0 || n/8 > 0x1fff {
+		return errInvalidFragOff
+	}
+	h.FragOff = n / 8
+	return nil
+}
+
+// SameFragmentGroup reports whether h and o are the headers of
+// fragments of the same datagram, that is whether they have the same
+// source address, destination address, identification and protocol
+// as described in RFC 791.
+func (h *Header) SameFragmentGroup(o *Header) bool {
+	if h == nil || o == nil {
+		return false
+	}
+	return h.ID == o.ID && h.Protocol == o.Protocol && h.Src.Equal(o.Src) && h.Dst.Equal(o.Dst)
+}
+
+// NewIDGenerator returns a function that generates values for the
+// ID field of IPv4 headers, such as the ones passed to the WriteTo
+// method of RawConn, which transmits a non-zero ID as is.  The
+// returned function is safe for concurrent use by multiple
+// goroutines, and each generator keeps its own state.
+//
+// The generated values increase by one from 1 to 65535, and then
+// wrap around to 1; zero is never generated since it lets the
+// platform choose the identification.  Thus a generator yields
+// 65535 distinct values before repeating itself, which bounds the
+// number of datagrams, fragmented or not, that may be in flight
+// between the same source and destination for the same protocol.
+func NewIDGenerator() func() int {
+	var mu sync.Mutex
+	id := 0
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if id == 0xffff {
+			id = 0
+		}
+		id++
+		return id
+	}
+}
+
+// EstimateHops returns the number of hops a received IPv4 datagram
+// likely traveled, guessed from the time-to-live field value
+// receivedTTL of the datagram, such as the TTL field of the control
+// message returned by ReadFrom with FlagTTL set.  The initial TTL is
+// assumed to be the smallest of the common initial values, 64, 128
+// and 255, that is not less than receivedTTL.  It returns -1 when
+// receivedTTL is out of the range of 0 to 255.
+//
+// It is a heuristic.  The guess is wrong when the sender uses an
+// uncommon initial value, such as 32 or 60 on some legacy systems,
+// or a value configured by the application, or when the datagram
+// traveled more hops than the difference between two common initial
+// values, such as 65 or more hops from a sender starting at 128.
+// Middleboxes that rewrite the TTL defeat it too.
+func EstimateHops(receivedTTL int) int {
+	if receivedTTL < 0 || receivedTTL > 255 {
+		return -1
+	}
+	for _, initial := range []int{64, 128, 255} {
+		if receivedTTL <= initial {
+			return initial - receivedTTL
+		}
+	}
+	return -1
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	nb := make([]byte, len(b))
+	copy(nb, b)
+	return nb
+}
+
 // Please refer to the online manual; IP(4) on Darwin, FreeBSD and
 // OpenBSD.  IP(7) on Linux.
 const supportsNewIPInput = runtime.GOOS == "linux" || runtime.GOOS == "openbsd"
 
+// RawReadsIncludeHeader reports whether the datagrams read from raw
+// IPv4 endpoints, such as the ones created by net.ListenPacket with
+// "ip4:icmp", through the Read or ReadMsgIP method of net.IPConn
+// begin with the IPv4 header on the running platform.  It is true on
+// all the platforms that provide raw sockets.
+//
+// It is independent of the reads through the other methods: the
+// ReadFrom method of net.IPConn and the ReadFrom method of PacketConn
+// always strip the header, while the ReadFrom method of RawConn
+// always returns it separately.  Note that the non-privileged
+// datagram-oriented ICMP endpoints, such as the ones created by
+// ListenICMPWithID, deliver no header on Linux.
+func RawReadsIncludeHeader() bool {
+	return rawReadsIncludeHeader
+}
+
+const rawReadsIncludeHeader = runtime.GOOS != "nacl" && runtime.GOOS != "plan9"
+
 // Marshal returns the binary encoding of the IPv4 header h.
 func (h *Header) Marshal() ([]byte, error) {
+	return h.marshal(!supportsNewIPInput)
+}
+
+// marshal returns the binary encoding of the IPv4 header h.  When
+// kernel is true, it encodes the total length and fragment offset
+// fields in the form taken by the traditional BSD kernels instead of
+// the wire format.
+func (h *Header) marshal(kernel bool) ([]byte, error) {
 	if h == nil {
 		return nil, syscall.EINVAL
 	}
 	if h.Len < HeaderLen {
-		return nil, errHeaderTooShort
+		return nil, ErrHeaderTooShort
 	}
 	hdrlen := HeaderLen + len(h.Options)
 	b := make([]byte, hdrlen)
 	b[0] = byte(Version<<4 | (hdrlen >> 2 & 0x0f))
 	b[posTOS] = byte(h.TOS)
 	flagsAndFragOff := (h.FragOff & 0x1fff) | int(h.Flags<<13)
-	if supportsNewIPInput {
+	if !kernel {
 		b[posTotalLen], b[posTotalLen+1] = byte(h.TotalLen>>8), byte(h.TotalLen)
 		b[posFragOff], b[posFragOff+1] = byte(flagsAndFragOff>>8), byte(flagsAndFragOff)
 	} else {
@@ -114,7 +249,7 @@ func (h *Header) Marshal() ([]byte, error) {
 	if ip := h.Dst.To4(); ip != nil {
 		copy(b[posDst:posDst+net.IPv4len], ip[:net.IPv4len])
 	} else {
-		return nil, errMissingAddress
+		return nil, ErrMissingAddress
 	}
 	if len(h.Options) > 0 {
 		copy(b[HeaderLen:], h.Options)
@@ -125,12 +260,30 @@ func (h *Header) Marshal() ([]byte, error) {
 // See http://www.freebsd.org/doc/en/books/porters-handbook/freebsd-versions.html.
 var freebsdVersion uint32
 
-// ParseHeader parses b as an IPv4 header.
+// ParseHeader parses b as an IPv4 header.  It returns an error when
+// the header length field is shorter than HeaderLen or longer than
+// b, or when the total length field is shorter than the header
+// length.
+//
+// The returned header copies the addresses and the options out of b
+// and doesn't alias it, so b may be reused once ParseHeader returns.
+// Use Clone to hand a header over to another goroutine when the
+// header itself may still be modified.
 func ParseHeader(b []byte) (*Header, error) {
+	return parseHeader(b, !supportsNewIPInput)
+}
+
+// parseHeader parses b as an IPv4 header.  When kernel is true, it
+// takes the total length and fragment offset fields in the form
+// passed by the traditional BSD kernels instead of the wire format.
+func parseHeader(b []byte, kernel bool) (*Header, error) {
 	if len(b) < HeaderLen {
-		return nil, errHeaderTooShort
+		return nil, ErrHeaderTooShort
 	}
 	hdrlen := int(b[0]&0x0f) << 2
+	if hdrlen < HeaderLen {
+		return nil, ErrHeaderTooShort
+	}
 	if hdrlen > len(b) {
 		return nil, errBufferTooShort
 	}
@@ -138,7 +291,7 @@ func ParseHeader(b []byte) (*Header, error) {
 	h.Version = int(b[0] >> 4)
 	h.Len = hdrlen
 	h.TOS = int(b[posTOS])
-	if supportsNewIPInput {
+	if !kernel {
 		h.TotalLen = int(b[posTotalLen])<<8 | int(b[posTotalLen+1])
 		h.FragOff = int(b[posFragOff])<<8 | int(b[posFragOff+1])
 	} else {
@@ -150,6 +303,9 @@ func ParseHeader(b []byte) (*Header, error) {
 		// TODO(mikio): fix potential misaligned memory access
 		h.FragOff = int(*(*uint16)(unsafe.Pointer(&b[posFragOff : posFragOff+1][0])))
 	}
+	if h.TotalLen < hdrlen {
+		return nil, errInvalidTotalLen
+	}
 	h.Flags = HeaderFlags(h.FragOff&0xe000) >> 13
 	h.FragOff = h.FragOff & 0x1fff
 	h.ID = int(b[posID])<<8 | int(b[posID+1])
@@ -160,7 +316,7 @@ func ParseHeader(b []byte) (*Header, error) {
 	h.Dst = net.IPv4(b[posDst], b[posDst+1], b[posDst+2], b[posDst+3])
 	if hdrlen-HeaderLen > 0 {
 		h.Options = make([]byte, hdrlen-HeaderLen)
-		copy(h.Options, b[HeaderLen:])
+		copy(h.Options, b[HeaderLen:hdrlen])
 	}
 	return h, nil
 }
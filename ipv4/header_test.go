@@ -83,6 +83,60 @@ func TestMarshalHeader(t *testing.T) {
 	}
 }
 
+func TestMarshalHeaderID(t *testing.T) {
+	for _, id := range []int{1, 0xcafe, 0xffff} {
+		h := *testHeader
+		h.ID = id
+		b, err := h.Marshal()
+		if err != nil {
+			t.Fatalf("ipv4.Header.Marshal failed: %v", err)
+		}
+		if got := int(b[posID])<<8 | int(b[posID+1]); got != id {
+			t.Fatalf("got %#04x; expected %#04x", got, id)
+		}
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	gen := NewIDGenerator()
+	for i := 1; i <= 0xffff; i++ {
+		if id := gen(); id != i {
+			t.Fatalf("got %v; expected %v", id, i)
+		}
+	}
+	if id := gen(); id != 1 {
+		t.Fatalf("got %v after wraparound; expected 1", id)
+	}
+	if id := NewIDGenerator()(); id != 1 {
+		t.Fatalf("got %v from new generator; expected 1", id)
+	}
+}
+
+var estimateHopsTests = []struct {
+	ttl, hops int
+}{
+	{64, 0},
+	{57, 7},
+	{1, 63},
+	{0, 64},
+	{65, 63},
+	{128, 0},
+	{113, 15},
+	{129, 126},
+	{255, 0},
+	{240, 15},
+	{-1, -1},
+	{256, -1},
+}
+
+func TestEstimateHops(t *testing.T) {
+	for _, tt := range estimateHopsTests {
+		if hops := EstimateHops(tt.ttl); hops != tt.hops {
+			t.Errorf("ttl %v: got %v; expected %v", tt.ttl, hops, tt.hops)
+		}
+	}
+}
+
 func TestParseHeader(t *testing.T) {
 	var wh []byte
 	if supportsNewIPInput {
@@ -102,3 +156,141 @@ func TestParseHeader(t *testing.T) {
 		t.Fatalf("ipv4.ParseHeader failed: %#v not equal %#v", h, testHeader)
 	}
 }
+
+var parseMalformedHeaderTests = []struct {
+	wh  []byte
+	err error
+}{
+	{wireHeaderFromKernel[:HeaderLen-1], ErrHeaderTooShort},
+	{[]byte{
+		0x44, 0x01, 0x00, 0x14,
+		0xca, 0xfe, 0x00, 0x00,
+		0xff, 0x01, 0xde, 0xad,
+		172, 16, 254, 254,
+		192, 168, 0, 1,
+	}, ErrHeaderTooShort},
+	{[]byte{
+		0x4f, 0x01, 0x00, 0x3c,
+		0xca, 0xfe, 0x00, 0x00,
+		0xff, 0x01, 0xde, 0xad,
+		172, 16, 254, 254,
+		192, 168, 0, 1,
+		0x01, 0x01, 0x01, 0x01,
+	}, errBufferTooShort},
+}
+
+func TestParseMalformedHeader(t *testing.T) {
+	for _, tt := range parseMalformedHeaderTests {
+		if _, err := ParseHeader(tt.wh); err != tt.err {
+			t.Errorf("ipv4.ParseHeader(%#v) failed: %v; expected %v", tt.wh, err, tt.err)
+		}
+		if _, _, err := slicePacket(tt.wh); err != tt.err {
+			t.Errorf("slicePacket(%#v) failed: %v; expected %v", tt.wh, err, tt.err)
+		}
+	}
+	if supportsNewIPInput {
+		wh := wireHeaderFromKernel
+		wh[posTotalLen], wh[posTotalLen+1] = 0, HeaderLen-1
+		if _, err := ParseHeader(wh[:]); err != errInvalidTotalLen {
+			t.Errorf("ipv4.ParseHeader(%#v) failed: %v; expected %v", wh, err, errInvalidTotalLen)
+		}
+	}
+}
+
+func TestParseTruncatedHeader(t *testing.T) {
+	b := append(wireHeaderFromKernel[:], 0x01, 0x01, 0x01, 0x01)
+	b[0] = 0x46
+	for i := 0; i < len(b); i++ {
+		ParseHeader(b[:i]) // must not panic
+		slicePacket(b[:i])
+	}
+	for ihl := byte(0); ihl < 0x10; ihl++ {
+		b[0] = 0x40 | ihl
+		ParseHeader(b)
+		slicePacket(b)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	b := []byte{
+		0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00,
+		0x40, 0x11, 0xb8, 0x61, 0xc0, 0xa8, 0x00, 0x01,
+		0xc0, 0xa8, 0x00, 0xc7,
+	}
+	if cs := checksum(b); cs != 0 {
+		t.Fatalf("got %#04x; expected 0", cs)
+	}
+	b[posChecksum], b[posChecksum+1] = 0, 0
+	if cs := checksum(b); cs != 0xb861 {
+		t.Fatalf("got %#04x; expected %#04x", cs, 0xb861)
+	}
+}
+
+func TestHeaderClone(t *testing.T) {
+	if h := (*Header)(nil).Clone(); h != nil {
+		t.Fatalf("got %v; expected <nil>", h)
+	}
+	h := &Header{
+		Version:  Version,
+		Len:      HeaderLen + 4,
+		TOS:      1,
+		TotalLen: 0xbeef,
+		ID:       0xcafe,
+		TTL:      255,
+		Protocol: 1,
+		Src:      net.IPv4(172, 16, 254, 254),
+		Dst:      net.IPv4(192, 168, 0, 1),
+		Options:  []byte{0x94, 0x04, 0x00, 0x00},
+	}
+	nh := h.Clone()
+	if !reflect.DeepEqual(nh, h) {
+		t.Fatalf("got %#v; expected %#v", nh, h)
+	}
+	h.Src[15], h.Dst[15], h.Options[0] = 0, 0, 0
+	if nh.Src[15] != 254 || nh.Dst[15] != 1 || nh.Options[0] != 0x94 {
+		t.Fatalf("got %#v; clone shares memory with original", nh)
+	}
+}
+
+func TestHeaderFragmentByteOffset(t *testing.T) {
+	var h Header
+	for _, n := range []int{0, 8, 1480, 65528} {
+		if err := h.SetFragmentByteOffset(n); err != nil {
+			t.Fatalf("Header.SetFragmentByteOffset(%v) failed: %v", n, err)
+		}
+		if h.FragOff != n/8 || h.FragmentByteOffset() != n {
+			t.Fatalf("got %v, %v; expected %v, %v", h.FragOff, h.FragmentByteOffset(), n/8, n)
+		}
+	}
+	for _, n := range []int{-8, 1, 1481, 65536} {
+		if err := h.SetFragmentByteOffset(n); err == nil {
+			t.Fatalf("Header.SetFragmentByteOffset(%v) succeeded", n)
+		}
+		if h.FragOff != 65528/8 {
+			t.Fatalf("got %v; expected %v", h.FragOff, 65528/8)
+		}
+	}
+}
+
+func TestHeaderSameFragmentGroup(t *testing.T) {
+	h := &Header{ID: 0xcafe, Protocol: 17, Src: net.IPv4(192, 0, 2, 1), Dst: net.IPv4(198, 51, 100, 1), FragOff: 0, Flags: MoreFragments}
+	o := &Header{ID: 0xcafe, Protocol: 17, Src: net.IPv4(192, 0, 2, 1).To4(), Dst: net.IPv4(198, 51, 100, 1).To4(), FragOff: 185}
+	if !h.SameFragmentGroup(o) {
+		t.Fatalf("%v and %v are not grouped", h, o)
+	}
+	o.Protocol = 6
+	if h.SameFragmentGroup(o) {
+		t.Fatalf("%v and %v with different protocols are grouped", h, o)
+	}
+	o.Protocol, o.ID = 17, 0xbeef
+	if h.SameFragmentGroup(o) {
+		t.Fatalf("%v and %v with different identifications are grouped", h, o)
+	}
+	o.ID, o.Dst = 0xcafe, net.IPv4(198, 51, 100, 2)
+	if h.SameFragmentGroup(o) {
+		t.Fatalf("%v and %v with different destinations are grouped", h, o)
+	}
+	if h.SameFragmentGroup(nil) {
+		t.Fatal("header is grouped with nil")
+	}
+}
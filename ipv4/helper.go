@@ -9,10 +9,15 @@ import (
 	"net"
 )
 
+// ErrNotSupported is returned when the requested operation or
+// option is not supported on the platform.
+var ErrNotSupported = errors.New("operation not supported")
+
 var (
-	errOpNoSupport              = errors.New("operation not supported")
 	errNoSuchInterface          = errors.New("no such interface")
 	errNoSuchMulticastInterface = errors.New("no such multicast interface")
+	errNonIPv4Address           = errors.New("non-IPv4 address")
+	errNonLocalAddress          = errors.New("non-local address")
 )
 
 func boolint(b bool) int {
@@ -22,6 +27,22 @@ func boolint(b bool) int {
 	return 0
 }
 
+// checksum returns the Internet checksum of b, as specified in RFC
+// 1071.  It returns zero when b carries a correct checksum.
+func checksum(b []byte) uint16 {
+	var s uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s>>16 != 0 {
+		s = s&0xffff + s>>16
+	}
+	return ^uint16(s)
+}
+
 func netAddrToIP4(a net.Addr) net.IP {
 	switch v := a.(type) {
 	case *net.UDPAddr:
@@ -35,3 +56,30 @@ func netAddrToIP4(a net.Addr) net.IP {
 	}
 	return nil
 }
+
+// netAddrToNetAddr4 returns a copy of a that carries the 4-byte form
+// of the IPv4 or IPv4-mapped IPv6 address.  It returns an error when
+// a doesn't carry any IPv4 address.
+func netAddrToNetAddr4(a net.Addr) (net.Addr, error) {
+	switch v := a.(type) {
+	case *net.UDPAddr:
+		if len(v.IP) == 0 {
+			return nil, ErrMissingAddress
+		}
+		ip := v.IP.To4()
+		if ip == nil {
+			return nil, errNonIPv4Address
+		}
+		return &net.UDPAddr{IP: ip, Port: v.Port, Zone: v.Zone}, nil
+	case *net.IPAddr:
+		if len(v.IP) == 0 {
+			return nil, ErrMissingAddress
+		}
+		ip := v.IP.To4()
+		if ip == nil {
+			return nil, errNonIPv4Address
+		}
+		return &net.IPAddr{IP: ip, Zone: v.Zone}, nil
+	}
+	return a, nil
+}
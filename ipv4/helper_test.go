@@ -0,0 +1,37 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+var netAddrToNetAddr4Tests = []struct {
+	in, out net.Addr
+	err     error
+}{
+	{&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1024}, &net.UDPAddr{IP: net.IP{192, 0, 2, 1}, Port: 1024}, nil},
+	{&net.UDPAddr{IP: net.IP{192, 0, 2, 1}, Port: 1024}, &net.UDPAddr{IP: net.IP{192, 0, 2, 1}, Port: 1024}, nil},
+	{&net.IPAddr{IP: net.ParseIP("::ffff:192.0.2.1")}, &net.IPAddr{IP: net.IP{192, 0, 2, 1}}, nil},
+
+	{&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1024}, nil, errNonIPv4Address},
+	{&net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, nil, errNonIPv4Address},
+	{&net.UDPAddr{Port: 1024}, nil, ErrMissingAddress},
+}
+
+func TestNetAddrToNetAddr4(t *testing.T) {
+	for _, tt := range netAddrToNetAddr4Tests {
+		a, err := netAddrToNetAddr4(tt.in)
+		if err != tt.err {
+			t.Errorf("netAddrToNetAddr4(%v) failed: %v; expected %v", tt.in, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(a, tt.out) {
+			t.Errorf("got %#v; expected %#v", a, tt.out)
+		}
+	}
+}
@@ -12,23 +12,23 @@ import (
 )
 
 func (c *genericOpt) sysfd() (int, error) {
-	switch p := c.Conn.(type) {
+	switch p := c.conn().(type) {
 	case *net.TCPConn, *net.UDPConn, *net.IPConn:
 		return sysfd(p)
 	}
-	return 0, errInvalidConnType
+	return 0, ErrInvalidConnType
 }
 
 func (c *dgramOpt) sysfd() (int, error) {
-	switch p := c.PacketConn.(type) {
+	switch p := c.conn().(type) {
 	case *net.UDPConn, *net.IPConn:
 		return sysfd(p.(net.Conn))
 	}
-	return 0, errInvalidConnType
+	return 0, ErrInvalidConnType
 }
 
 func (c *payloadHandler) sysfd() (int, error) {
-	return sysfd(c.PacketConn.(net.Conn))
+	return sysfd(c.conn().(net.Conn))
 }
 
 func (c *packetHandler) sysfd() (int, error) {
@@ -46,5 +46,5 @@ func sysfd(c net.Conn) (int, error) {
 			return int(fd.Int()), nil
 		}
 	}
-	return 0, errInvalidConnType
+	return 0, ErrInvalidConnType
 }
@@ -11,23 +11,23 @@ import (
 )
 
 func (c *genericOpt) sysfd() (syscall.Handle, error) {
-	switch p := c.Conn.(type) {
+	switch p := c.conn().(type) {
 	case *net.TCPConn, *net.UDPConn, *net.IPConn:
 		return sysfd(p)
 	}
-	return syscall.InvalidHandle, errInvalidConnType
+	return syscall.InvalidHandle, ErrInvalidConnType
 }
 
 func (c *dgramOpt) sysfd() (syscall.Handle, error) {
-	switch p := c.PacketConn.(type) {
+	switch p := c.conn().(type) {
 	case *net.UDPConn, *net.IPConn:
 		return sysfd(p.(net.Conn))
 	}
-	return syscall.InvalidHandle, errInvalidConnType
+	return syscall.InvalidHandle, ErrInvalidConnType
 }
 
 func (c *payloadHandler) sysfd() (syscall.Handle, error) {
-	return sysfd(c.PacketConn.(net.Conn))
+	return sysfd(c.conn().(net.Conn))
 }
 
 func (c *packetHandler) sysfd() (syscall.Handle, error) {
@@ -45,5 +45,5 @@ func sysfd(c net.Conn) (syscall.Handle, error) {
 			return syscall.Handle(fd.Uint()), nil
 		}
 	}
-	return syscall.InvalidHandle, errInvalidConnType
+	return syscall.InvalidHandle, ErrInvalidConnType
 }
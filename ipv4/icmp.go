@@ -4,6 +4,8 @@
 
 package ipv4
 
+import "sync"
+
 // An ICMPType represents a type of ICMP message.
 type ICMPType int
 
@@ -14,3 +16,71 @@ func (typ ICMPType) String() string {
 	}
 	return s
 }
+
+// An ICMPFilter represents an ICMP message filter for incoming
+// packets.  The filter applies to ICMP types 0 through 31; messages
+// of other types are never blocked.
+type ICMPFilter struct {
+	mu sync.RWMutex
+	sysICMPFilter
+}
+
+// Set sets the ICMP type and filter action to the filter.
+func (f *ICMPFilter) Set(typ ICMPType, block bool) {
+	f.mu.Lock()
+	f.set(typ, block)
+	f.mu.Unlock()
+}
+
+// SetAll sets the filter action to the filter.
+func (f *ICMPFilter) SetAll(block bool) {
+	f.mu.Lock()
+	f.setAll(block)
+	f.mu.Unlock()
+}
+
+// Accept sets the filter to accept the ICMP type.
+func (f *ICMPFilter) Accept(typ ICMPType) {
+	f.Set(typ, false)
+}
+
+// Block sets the filter to block the ICMP type.
+func (f *ICMPFilter) Block(typ ICMPType) {
+	f.Set(typ, true)
+}
+
+// WillBlock reports whether the ICMP type will be blocked.
+func (f *ICMPFilter) WillBlock(typ ICMPType) bool {
+	f.mu.RLock()
+	ok := f.willBlock(typ)
+	f.mu.RUnlock()
+	return ok
+}
+
+// An ICMPErrField represents the meaning of the 32-bit field
+// following the checksum field of an ICMP error message, which
+// depends on the type and code of the message.
+type ICMPErrField int
+
+const (
+	ICMPErrFieldNone    ICMPErrField = iota // not an error message
+	ICMPErrFieldUnused                      // unused field, which must be zero
+	ICMPErrFieldMTU                         // next-hop MTU of fragmentation needed, or MTU of IPv6 packet too big
+	ICMPErrFieldGateway                     // gateway address of redirect
+	ICMPErrFieldPointer                     // pointer to the octet in error of parameter problem
+)
+
+var icmpErrFieldNames = []string{
+	ICMPErrFieldNone:    "none",
+	ICMPErrFieldUnused:  "unused",
+	ICMPErrFieldMTU:     "mtu",
+	ICMPErrFieldGateway: "gateway",
+	ICMPErrFieldPointer: "pointer",
+}
+
+func (f ICMPErrField) String() string {
+	if f < 0 || int(f) >= len(icmpErrFieldNames) {
+		return "<nil>"
+	}
+	return icmpErrFieldNames[f]
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+func (f *sysICMPFilter) set(typ ICMPType, block bool) {
+	if typ < 0 || typ > 31 {
+		return
+	}
+	if block {
+		f.Data |= 1 << uint32(typ)
+	} else {
+		f.Data &^= 1 << uint32(typ)
+	}
+}
+
+func (f *sysICMPFilter) setAll(block bool) {
+	if block {
+		f.Data = 1<<32 - 1
+	} else {
+		f.Data = 0
+	}
+}
+
+func (f *sysICMPFilter) willBlock(typ ICMPType) bool {
+	if typ < 0 || typ > 31 {
+		return false
+	}
+	return f.Data&(1<<uint32(typ)) != 0
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd windows
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// ICMPEchoID returns the identifier that the kernel puts into the
+// ICMP echo messages transmitted through the endpoint.
+//
+// On Linux, a non-privileged datagram-oriented ICMP endpoint, also
+// known as ping socket, overwrites the identifier field of outgoing
+// ICMP echo request messages with the local port number of the
+// socket, and delivers only the ICMP echo reply messages whose
+// identifier field matches the port number.  An application using
+// such endpoint should use the returned value to correlate replies
+// instead of the identifier it specified.  The local port number is
+// assigned when the endpoint is bound or, if it's not bound, when
+// the first message is transmitted; ICMPEchoID returns zero before
+// the assignment.
+//
+// A raw ICMP endpoint, such as the one created by ListenPacket with
+// "ip4:icmp", transmits the identifier as specified, and ICMPEchoID
+// returns zero on it.
+func (c *PacketConn) ICMPEchoID() (int, error) {
+	if !c.payloadHandler.ok() {
+		return 0, syscall.EINVAL
+	}
+	if _, ok := c.payloadHandler.conn().(*net.IPConn); ok {
+		return 0, nil
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return 0, err
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockname", err)
+	}
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return sa.Port, nil
+	}
+	return 0, ErrInvalidConnType
+}
@@ -0,0 +1,181 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+func TestPacketConnICMPEchoID(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+
+	id, err := ipv4.NewPacketConn(c).ICMPEchoID()
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ICMPEchoID failed: %v", err)
+	}
+	if port := c.LocalAddr().(*net.UDPAddr).Port; id != port {
+		t.Fatalf("got %v; expected %v", id, port)
+	}
+}
+
+func TestICMPFilter(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	var f ipv4.ICMPFilter
+	for _, toggle := range []bool{false, true} {
+		f.SetAll(toggle)
+		var wg sync.WaitGroup
+		for _, typ := range []ipv4.ICMPType{
+			ipv4.ICMPTypeDestinationUnreachable,
+			ipv4.ICMPTypeEchoReply,
+			ipv4.ICMPTypeTimeExceeded,
+			ipv4.ICMPTypeParameterProblem,
+		} {
+			wg.Add(1)
+			go func(typ ipv4.ICMPType) {
+				defer wg.Done()
+				f.Accept(typ)
+				if f.WillBlock(typ) {
+					t.Errorf("ipv4.ICMPFilter.Accept(%v) failed", typ)
+				}
+				f.Block(typ)
+				if !f.WillBlock(typ) {
+					t.Errorf("ipv4.ICMPFilter.Block(%v) failed", typ)
+				}
+			}(typ)
+		}
+		wg.Wait()
+	}
+}
+
+func TestPacketConnSetICMPFilterNil(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := ipv4.NewPacketConn(c).SetICMPFilter(nil); err != syscall.EINVAL {
+		t.Fatalf("got %v; expected %v", err, syscall.EINVAL)
+	}
+}
+
+func TestPacketConnICMPFilter(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	var f ipv4.ICMPFilter
+	f.SetAll(false)
+	f.Block(ipv4.ICMPTypeEchoReply)
+	f.Block(ipv4.ICMPTypeTimeExceeded)
+	if err := p.SetICMPFilter(&f); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetICMPFilter failed: %v", err)
+	}
+	kf, err := p.ICMPFilter()
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ICMPFilter failed: %v", err)
+	}
+	for typ := ipv4.ICMPType(0); typ < 32; typ++ {
+		if kf.WillBlock(typ) != f.WillBlock(typ) {
+			t.Errorf("%v: got %v; expected %v", typ, kf.WillBlock(typ), f.WillBlock(typ))
+		}
+	}
+
+	// The echo request looped back to the endpoint is delivered,
+	// while the echo reply from the protocol stack is dropped.
+	wb, err := ipv4.NewEchoRequest(os.Getpid()&0xffff, 1, []byte("HELLO-R-U-THERE"))
+	if err != nil {
+		t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+	}
+	if _, err := p.WriteTo(wb, nil, &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	sawRequest := false
+	rb := make([]byte, 128)
+	for {
+		n, _, _, err := p.ReadFrom(rb)
+		if err != nil {
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+			}
+			break
+		}
+		if n == 0 {
+			continue
+		}
+		switch ipv4.ICMPType(rb[0]) {
+		case ipv4.ICMPTypeEcho:
+			sawRequest = true
+		case ipv4.ICMPTypeEchoReply:
+			t.Fatal("got blocked echo reply")
+		}
+	}
+	if !sawRequest {
+		t.Fatal("got no echo request")
+	}
+}
+
+func TestPacketConnICMPEchoIDRawICMP(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+
+	// The raw endpoint reports the protocol number as its local
+	// port, which must not be mistaken for an identifier.
+	id, err := ipv4.NewPacketConn(c).ICMPEchoID()
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ICMPEchoID failed: %v", err)
+	}
+	if id != 0 {
+		t.Fatalf("got %v; expected 0", id)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd windows
+
+package ipv4
+
+import "net"
+
+// InterfaceMulticastGroups returns the IPv4 multicast groups joined
+// on the network interface ifi by any endpoint in the system, not
+// only the ones joined through JoinGroup of the package.  When ifi
+// is nil, it returns the groups joined on all interfaces.  It is
+// intended for diagnostics; the groups are read from the system,
+// such as /proc/net/igmp on Linux, by the net package.
+func InterfaceMulticastGroups(ifi *net.Interface) ([]net.IP, error) {
+	var ift []net.Interface
+	if ifi != nil {
+		ift = []net.Interface{*ifi}
+	} else {
+		var err error
+		if ift, err = net.Interfaces(); err != nil {
+			return nil, err
+		}
+	}
+	var grps []net.IP
+	for _, ifi := range ift {
+		ifmat, err := ifi.MulticastAddrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifma := range ifmat {
+			var ip net.IP
+			switch ifma := ifma.(type) {
+			case *net.IPAddr:
+				ip = ifma.IP
+			case *net.IPNet:
+				ip = ifma.IP
+			}
+			if ip := ip.To4(); ip != nil && ip.IsMulticast() {
+				grps = append(grps, ip)
+			}
+		}
+	}
+	return grps, nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build nacl plan9 solaris
+
+package ipv4
+
+import "net"
+
+// InterfaceMulticastGroups returns the IPv4 multicast groups joined
+// on the network interface ifi by any endpoint in the system.
+// It is not supported on this platform.
+func InterfaceMulticastGroups(ifi *net.Interface) ([]net.IP, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,376 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package igmp provides basic functions for the manipulation of IGMP
+// messages.
+//
+// IGMP messages are carried in IPv4 datagrams with the protocol
+// number 2, a TTL of 1 and the IP Router Alert option, which
+// RouterAlertOption returns in the form suitable for the Options
+// field of ipv4.Header.  The package supports IGMPv1, IGMPv2 and
+// IGMPv3 as specified in RFC 1112, RFC 2236 and RFC 3376.
+package igmp
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+var (
+	errMessageTooShort = errors.New("message too short")
+	errInvalidChecksum = errors.New("invalid checksum")
+	errInvalidGroup    = errors.New("invalid group address")
+	errInvalidSource   = errors.New("invalid source address")
+	errUnknownType     = errors.New("unknown message type")
+	errTooManySources  = errors.New("too many sources")
+)
+
+// A Type represents a type of IGMP message.
+type Type int
+
+// IGMP message types
+const (
+	TypeMembershipQuery    Type = 0x11 // membership query, IGMPv1, v2 and v3
+	TypeV1MembershipReport Type = 0x12 // version 1 membership report
+	TypeV2MembershipReport Type = 0x16 // version 2 membership report
+	TypeV2LeaveGroup       Type = 0x17 // version 2 leave group
+	TypeV3MembershipReport Type = 0x22 // version 3 membership report
+)
+
+var types = map[Type]string{
+	TypeMembershipQuery:    "membership query",
+	TypeV1MembershipReport: "version 1 membership report",
+	TypeV2MembershipReport: "version 2 membership report",
+	TypeV2LeaveGroup:       "version 2 leave group",
+	TypeV3MembershipReport: "version 3 membership report",
+}
+
+func (typ Type) String() string {
+	s, ok := types[typ]
+	if !ok {
+		return "<nil>"
+	}
+	return s
+}
+
+// A Message represents an IGMP message.
+type Message interface {
+	// Type returns the type of the message.
+	Type() Type
+
+	// Marshal returns the binary encoding of the message,
+	// including the calculated checksum field.
+	Marshal() ([]byte, error)
+}
+
+// A Query represents an IGMPv1 or IGMPv2 membership query message.
+// It is a general query when Group is nil or the unspecified address.
+type Query struct {
+	MaxRespTime time.Duration // maximum response time, zero on IGMPv1
+	Group       net.IP        // group address
+}
+
+// Type implements the Type method of Message interface.
+func (q *Query) Type() Type { return TypeMembershipQuery }
+
+// Marshal implements the Marshal method of Message interface.
+func (q *Query) Marshal() ([]byte, error) {
+	b := make([]byte, 8)
+	b[0] = byte(TypeMembershipQuery)
+	if t := q.MaxRespTime / (100 * time.Millisecond); t > 0xff {
+		b[1] = 0xff
+	} else {
+		b[1] = byte(t)
+	}
+	if err := putIP(b[4:8], q.Group, errInvalidGroup); err != nil {
+		return nil, err
+	}
+	setChecksum(b)
+	return b, nil
+}
+
+// A Report represents an IGMPv1 or IGMPv2 membership report
+// message, or an IGMPv2 leave group message.
+type Report struct {
+	MessageType Type   // TypeV1MembershipReport, TypeV2MembershipReport or TypeV2LeaveGroup
+	Group       net.IP // group address
+}
+
+// Type implements the Type method of Message interface.
+func (r *Report) Type() Type { return r.MessageType }
+
+// Marshal implements the Marshal method of Message interface.
+func (r *Report) Marshal() ([]byte, error) {
+	switch r.MessageType {
+	case TypeV1MembershipReport, TypeV2MembershipReport, TypeV2LeaveGroup:
+	default:
+		return nil, errUnknownType
+	}
+	b := make([]byte, 8)
+	b[0] = byte(r.MessageType)
+	if err := putIP(b[4:8], r.Group, errInvalidGroup); err != nil {
+		return nil, err
+	}
+	setChecksum(b)
+	return b, nil
+}
+
+// A QueryV3 represents an IGMPv3 membership query message.
+// It is a general query when Group is nil or the unspecified
+// address, and a group-and-source specific query when Sources is not
+// empty.
+type QueryV3 struct {
+	MaxRespTime   time.Duration // maximum response time
+	Group         net.IP        // group address
+	SuppressRoute bool          // suppress router-side processing
+	QRV           int           // querier's robustness variable
+	QQI           time.Duration // querier's query interval
+	Sources       []net.IP      // source addresses
+}
+
+// Type implements the Type method of Message interface.
+func (q *QueryV3) Type() Type { return TypeMembershipQuery }
+
+// Marshal implements the Marshal method of Message interface.
+func (q *QueryV3) Marshal() ([]byte, error) {
+	if len(q.Sources) > 0xffff {
+		return nil, errTooManySources
+	}
+	b := make([]byte, 12+4*len(q.Sources))
+	b[0] = byte(TypeMembershipQuery)
+	b[1] = encodeCode(int(q.MaxRespTime / (100 * time.Millisecond)))
+	if err := putIP(b[4:8], q.Group, errInvalidGroup); err != nil {
+		return nil, err
+	}
+	if q.SuppressRoute {
+		b[8] |= 0x08
+	}
+	if q.QRV > 0 && q.QRV <= 7 {
+		b[8] |= byte(q.QRV)
+	}
+	b[9] = encodeCode(int(q.QQI / time.Second))
+	b[10], b[11] = byte(len(q.Sources)>>8), byte(len(q.Sources))
+	for i, src := range q.Sources {
+		if err := putIP(b[12+4*i:16+4*i], src, errInvalidSource); err != nil {
+			return nil, err
+		}
+	}
+	setChecksum(b)
+	return b, nil
+}
+
+// A RecordType represents a type of IGMPv3 group record.
+type RecordType int
+
+// IGMPv3 group record types
+const (
+	ModeIsInclude       RecordType = 1 // MODE_IS_INCLUDE
+	ModeIsExclude       RecordType = 2 // MODE_IS_EXCLUDE
+	ChangeToIncludeMode RecordType = 3 // CHANGE_TO_INCLUDE_MODE
+	ChangeToExcludeMode RecordType = 4 // CHANGE_TO_EXCLUDE_MODE
+	AllowNewSources     RecordType = 5 // ALLOW_NEW_SOURCES
+	BlockOldSources     RecordType = 6 // BLOCK_OLD_SOURCES
+)
+
+// A GroupRecord represents an IGMPv3 group record.
+type GroupRecord struct {
+	Type    RecordType // record type
+	Group   net.IP     // multicast address
+	Sources []net.IP   // source addresses
+	AuxData []byte     // auxiliary data, must be a multiple of 4 bytes
+}
+
+func (r *GroupRecord) len() int {
+	return 8 + 4*len(r.Sources) + len(r.AuxData)
+}
+
+// A ReportV3 represents an IGMPv3 membership report message.
+type ReportV3 struct {
+	Records []GroupRecord // group records
+}
+
+// Type implements the Type method of Message interface.
+func (r *ReportV3) Type() Type { return TypeV3MembershipReport }
+
+// Marshal implements the Marshal method of Message interface.
+func (r *ReportV3) Marshal() ([]byte, error) {
+	if len(r.Records) > 0xffff {
+		return nil, errors.New("too many group records")
+	}
+	l := 8
+	for i := range r.Records {
+		if len(r.Records[i].Sources) > 0xffff {
+			return nil, errTooManySources
+		}
+		if len(r.Records[i].AuxData)%4 != 0 || len(r.Records[i].AuxData) > 0xff*4 {
+			return nil, errors.New("invalid auxiliary data length")
+		}
+		l += r.Records[i].len()
+	}
+	b := make([]byte, l)
+	b[0] = byte(TypeV3MembershipReport)
+	b[6], b[7] = byte(len(r.Records)>>8), byte(len(r.Records))
+	off := 8
+	for i := range r.Records {
+		rec := &r.Records[i]
+		rb := b[off : off+rec.len()]
+		rb[0] = byte(rec.Type)
+		rb[1] = byte(len(rec.AuxData) / 4)
+		rb[2], rb[3] = byte(len(rec.Sources)>>8), byte(len(rec.Sources))
+		if err := putIP(rb[4:8], rec.Group, errInvalidGroup); err != nil {
+			return nil, err
+		}
+		for j, src := range rec.Sources {
+			if err := putIP(rb[8+4*j:12+4*j], src, errInvalidSource); err != nil {
+				return nil, err
+			}
+		}
+		copy(rb[8+4*len(rec.Sources):], rec.AuxData)
+		off += len(rb)
+	}
+	setChecksum(b)
+	return b, nil
+}
+
+// ParseMessage parses b as an IGMP message.  It returns *Query or
+// *QueryV3 for membership queries, *Report for IGMPv1 and IGMPv2
+// reports and leave messages, and *ReportV3 for IGMPv3 reports.
+func ParseMessage(b []byte) (Message, error) {
+	if len(b) < 8 {
+		return nil, errMessageTooShort
+	}
+	if checksum(b) != 0 {
+		return nil, errInvalidChecksum
+	}
+	switch Type(b[0]) {
+	case TypeMembershipQuery:
+		if len(b) == 8 {
+			return &Query{MaxRespTime: time.Duration(b[1]) * 100 * time.Millisecond, Group: getIP(b[4:8])}, nil
+		}
+		return parseQueryV3(b)
+	case TypeV1MembershipReport, TypeV2MembershipReport, TypeV2LeaveGroup:
+		return &Report{MessageType: Type(b[0]), Group: getIP(b[4:8])}, nil
+	case TypeV3MembershipReport:
+		return parseReportV3(b)
+	}
+	return nil, errUnknownType
+}
+
+func parseQueryV3(b []byte) (*QueryV3, error) {
+	if len(b) < 12 {
+		return nil, errMessageTooShort
+	}
+	q := &QueryV3{
+		MaxRespTime:   time.Duration(decodeCode(b[1])) * 100 * time.Millisecond,
+		Group:         getIP(b[4:8]),
+		SuppressRoute: b[8]&0x08 != 0,
+		QRV:           int(b[8] & 0x07),
+		QQI:           time.Duration(decodeCode(b[9])) * time.Second,
+	}
+	n := int(b[10])<<8 | int(b[11])
+	if len(b) < 12+4*n {
+		return nil, errMessageTooShort
+	}
+	for i := 0; i < n; i++ {
+		q.Sources = append(q.Sources, getIP(b[12+4*i:16+4*i]))
+	}
+	return q, nil
+}
+
+func parseReportV3(b []byte) (*ReportV3, error) {
+	r := &ReportV3{}
+	n := int(b[6])<<8 | int(b[7])
+	b = b[8:]
+	for i := 0; i < n; i++ {
+		if len(b) < 8 {
+			return nil, errMessageTooShort
+		}
+		auxLen := int(b[1]) * 4
+		nsrcs := int(b[2])<<8 | int(b[3])
+		l := 8 + 4*nsrcs + auxLen
+		if len(b) < l {
+			return nil, errMessageTooShort
+		}
+		rec := GroupRecord{Type: RecordType(b[0]), Group: getIP(b[4:8])}
+		for j := 0; j < nsrcs; j++ {
+			rec.Sources = append(rec.Sources, getIP(b[8+4*j:12+4*j]))
+		}
+		if auxLen > 0 {
+			rec.AuxData = make([]byte, auxLen)
+			copy(rec.AuxData, b[8+4*nsrcs:l])
+		}
+		r.Records = append(r.Records, rec)
+		b = b[l:]
+	}
+	return r, nil
+}
+
+// RouterAlertOption returns the IPv4 Router Alert option, as
+// specified in RFC 2113, in the form suitable for the Options field
+// of ipv4.Header.
+func RouterAlertOption() []byte {
+	return []byte{0x94, 0x04, 0x00, 0x00}
+}
+
+// encodeCode encodes v as an IGMPv3 Max Resp Code or QQIC field
+// value, as specified in section 4.1.1 and 4.1.7 of RFC 3376.
+func encodeCode(v int) byte {
+	if v < 0x80 {
+		return byte(v)
+	}
+	for exp := 0; exp < 8; exp++ {
+		if mant := v>>uint(exp+3) - 0x10; mant < 0x10 {
+			return 0x80 | byte(exp)<<4 | byte(mant)
+		}
+	}
+	return 0xff
+}
+
+// decodeCode decodes the IGMPv3 Max Resp Code or QQIC field value b.
+func decodeCode(b byte) int {
+	if b < 0x80 {
+		return int(b)
+	}
+	mant, exp := int(b&0x0f), uint(b>>4&0x07)
+	return (mant | 0x10) << (exp + 3)
+}
+
+func putIP(b []byte, ip net.IP, err error) error {
+	if ip == nil {
+		return nil
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return err
+	}
+	copy(b, ip4)
+	return nil
+}
+
+func getIP(b []byte) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, b)
+	return ip
+}
+
+func setChecksum(b []byte) {
+	b[2], b[3] = 0, 0
+	s := checksum(b)
+	b[2], b[3] = byte(s>>8), byte(s)
+}
+
+func checksum(b []byte) uint16 {
+	var s uint32
+	for i := 0; i < len(b)-1; i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s>>16 != 0 {
+		s = s&0xffff + s>>16
+	}
+	return ^uint16(s)
+}
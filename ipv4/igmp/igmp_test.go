@@ -0,0 +1,93 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package igmp_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4/igmp"
+)
+
+var marshalAndParseMessageTests = []igmp.Message{
+	&igmp.Query{MaxRespTime: 10 * time.Second, Group: net.IPv4zero.To4()},
+	&igmp.Query{MaxRespTime: time.Second, Group: net.IP{239, 1, 2, 3}},
+	&igmp.Report{MessageType: igmp.TypeV1MembershipReport, Group: net.IP{224, 0, 0, 251}},
+	&igmp.Report{MessageType: igmp.TypeV2MembershipReport, Group: net.IP{239, 1, 2, 3}},
+	&igmp.Report{MessageType: igmp.TypeV2LeaveGroup, Group: net.IP{239, 1, 2, 3}},
+	&igmp.QueryV3{
+		MaxRespTime: 12800 * time.Millisecond,
+		Group:       net.IP{232, 1, 1, 1},
+		QRV:         2,
+		QQI:         125 * time.Second,
+		Sources:     []net.IP{{192, 0, 2, 1}, {192, 0, 2, 2}},
+	},
+	&igmp.QueryV3{
+		MaxRespTime:   time.Second,
+		Group:         net.IPv4zero.To4(),
+		SuppressRoute: true,
+		QRV:           7,
+		QQI:           31744 * time.Second,
+	},
+	&igmp.ReportV3{
+		Records: []igmp.GroupRecord{
+			{Type: igmp.ModeIsInclude, Group: net.IP{232, 1, 1, 1}, Sources: []net.IP{{192, 0, 2, 1}}},
+			{Type: igmp.ChangeToExcludeMode, Group: net.IP{239, 1, 2, 3}},
+			{Type: igmp.AllowNewSources, Group: net.IP{232, 1, 1, 2}, Sources: []net.IP{{198, 51, 100, 1}, {198, 51, 100, 2}}, AuxData: []byte{1, 2, 3, 4}},
+		},
+	},
+}
+
+func TestMarshalAndParseMessage(t *testing.T) {
+	for _, tt := range marshalAndParseMessageTests {
+		b, err := tt.Marshal()
+		if err != nil {
+			t.Fatalf("%v: %v", tt.Type(), err)
+		}
+		m, err := igmp.ParseMessage(b)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.Type(), err)
+		}
+		if !reflect.DeepEqual(m, tt) {
+			t.Errorf("got %#v; expected %#v", m, tt)
+		}
+	}
+}
+
+func TestParseMalformedMessage(t *testing.T) {
+	b, err := (&igmp.Report{MessageType: igmp.TypeV2MembershipReport, Group: net.IP{239, 1, 2, 3}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[7] ^= 0xff
+	if _, err := igmp.ParseMessage(b); err == nil {
+		t.Error("igmp.ParseMessage succeeded with invalid checksum")
+	}
+
+	b, err = (&igmp.QueryV3{Sources: []net.IP{{192, 0, 2, 1}}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(b); i++ {
+		if _, err := igmp.ParseMessage(b[:i]); err == nil {
+			t.Errorf("igmp.ParseMessage succeeded with %d-byte message", i)
+		}
+	}
+}
+
+func TestMarshalInvalidMessage(t *testing.T) {
+	for _, m := range []igmp.Message{
+		&igmp.Report{MessageType: igmp.TypeMembershipQuery},
+		&igmp.Report{MessageType: igmp.TypeV2MembershipReport, Group: net.ParseIP("ff02::1")},
+		&igmp.QueryV3{Sources: []net.IP{net.ParseIP("2001:db8::1")}},
+		&igmp.ReportV3{Records: []igmp.GroupRecord{{Type: igmp.ModeIsInclude, AuxData: []byte{1}}}},
+	} {
+		if _, err := m.Marshal(); err == nil {
+			t.Errorf("%#v.Marshal succeeded; expected error", m)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"time"
+)
+
+// SetCloseLinger is meant to set the duration d for which Close
+// waits for the datagrams written to the endpoint to leave the host.
+// It only validates d and does nothing else.
+//
+// The SO_LINGER socket option has no effect on datagram sockets: the
+// protocol stack neither waits for nor discards the datagrams queued
+// on close, and a datagram accepted by WriteTo is transmitted even
+// after the endpoint is closed, as long as the host keeps running.
+// Hence Close always returns immediately.
+func (c *PacketConn) SetCloseLinger(d time.Duration) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	if d < 0 {
+		return syscall.EINVAL
+	}
+	return nil
+}
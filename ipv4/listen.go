@@ -0,0 +1,56 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+	"strconv"
+)
+
+var errNotLinkLocalMulticast = errors.New("not link-local multicast address")
+
+// ListenLinkLocalMulticast listens for UDP datagrams sent to the
+// link-local multicast group, in the range of 224.0.0.0/24, on the
+// interface ifi.  The group must be *net.UDPAddr carrying the group
+// address and the port to listen on.
+//
+// The returned endpoint is bound to the group address and port with
+// the address and port reusable, so that the other applications
+// listening for the same group, such as mDNS responders, keep
+// working.  It joins the group on ifi, and uses ifi as the outgoing
+// interface for multicast datagrams, such as replies sent to the
+// group.  Since datagrams sent to link-local groups are never
+// forwarded, ifi must not be nil.
+func ListenLinkLocalMulticast(ifi *net.Interface, group net.Addr) (*PacketConn, error) {
+	if ifi == nil {
+		return nil, errNoSuchMulticastInterface
+	}
+	a, ok := group.(*net.UDPAddr)
+	if !ok || a == nil {
+		return nil, ErrMissingAddress
+	}
+	ip := a.IP.To4()
+	if ip == nil {
+		return nil, errNonIPv4Address
+	}
+	if !ip.IsLinkLocalMulticast() {
+		return nil, errNotLinkLocalMulticast
+	}
+	c, err := net.ListenPacket("udp4", net.JoinHostPort(ip.String(), strconv.Itoa(a.Port)))
+	if err != nil {
+		return nil, err
+	}
+	p := NewPacketConn(c)
+	if err := p.JoinGroup(ifi, &net.UDPAddr{IP: ip}); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if err := p.SetMulticastInterface(ifi); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/net/internal/iana"
+)
+
+var (
+	errICMPSocketNotPermitted = errors.New("datagram-oriented icmp socket not permitted; the group of the process must be in the range of net.ipv4.ping_group_range sysctl")
+	errICMPIDInUse            = errors.New("icmp identifier already in use")
+	errInvalidICMPID          = errors.New("icmp identifier out of range")
+)
+
+// ListenICMPWithID listens for ICMP messages on address using a
+// datagram-oriented, unprivileged ICMP socket and binds the socket
+// to use id as the identifier of outgoing ICMP echo requests.  The
+// network must be "udp4", and address may be an empty string, which
+// means the unspecified address, or a literal IPv4 address or a host
+// name.  Sending and receiving go through the returned PacketConn
+// with *net.UDPAddr addresses; the protocol stack fills in the
+// identifier and filters the replies by it.
+//
+// The id must be in the range of 0 to 65535, and zero lets the
+// protocol stack choose an identifier not in use.  The identifier is
+// the local port of the socket and therefore can be used by only one
+// socket at a time; ListenICMPWithID fails when another socket uses
+// id.  It also fails when the group of the process is outside the
+// range given by the net.ipv4.ping_group_range sysctl, which doesn't
+// allow any group by default on some distributions.
+// Currently only Linux supports this.
+func ListenICMPWithID(network, address string, id int) (*PacketConn, error) {
+	if network != "udp4" {
+		return nil, net.UnknownNetworkError(network)
+	}
+	if id < 0 || id > 0xffff {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: errInvalidICMPID}
+	}
+	sa := &syscall.SockaddrInet4{Port: id}
+	if address != "" {
+		a, err := net.ResolveIPAddr("ip4", address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := a.IP.To4(); ip != nil {
+			copy(sa.Addr[:], ip)
+		}
+	}
+	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, iana.ProtocolICMP)
+	if err != nil {
+		if err == syscall.EACCES || err == syscall.EPERM {
+			err = errICMPSocketNotPermitted
+		} else {
+			err = os.NewSyscallError("socket", err)
+		}
+		return nil, &net.OpError{Op: "listen", Net: network, Err: err}
+	}
+	if err := syscall.Bind(s, sa); err != nil {
+		syscall.Close(s)
+		if err == syscall.EADDRINUSE {
+			err = errICMPIDInUse
+		} else {
+			err = os.NewSyscallError("bind", err)
+		}
+		return nil, &net.OpError{Op: "listen", Net: network, Err: err}
+	}
+	f := os.NewFile(uintptr(s), "datagram-oriented icmp")
+	c, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	return NewPacketConn(c), nil
+}
@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+// ListenICMPWithID listens for ICMP messages on address using a
+// datagram-oriented, unprivileged ICMP socket and binds the socket
+// to use id as the identifier of outgoing ICMP echo requests.
+// Currently only Linux supports this.
+func ListenICMPWithID(network, address string, id int) (*PacketConn, error) {
+	return nil, ErrNotSupported
+}
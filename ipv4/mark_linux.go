@@ -0,0 +1,22 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// appendMark appends the SO_MARK control message carrying mark to
+// oob.
+func appendMark(oob []byte, mark uint32) []byte {
+	b := make([]byte, syscall.CmsgSpace(4))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = syscall.SOL_SOCKET
+	m.Type = syscall.SO_MARK
+	m.SetLen(syscall.CmsgLen(4))
+	*(*uint32)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = mark
+	return append(oob, b...)
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"strconv"
+)
+
+// An MTUError is held in the Err field of the *net.OpError returned
+// by the WriteTo method of PacketConn when the datagram is too large
+// to be sent without fragmentation.  It carries the path MTU cached by
+// the protocol stack for the endpoint, so that the application can
+// resize the datagram and retry, and wraps the error the Err field
+// would hold otherwise, which carries syscall.EMSGSIZE.
+//
+// The protocol stack knows the path MTU only for connected endpoints.
+// Currently only Linux reports it.  On other platforms, or when the
+// path MTU is unknown, the Err field holds the underlying error as is;
+// EffectiveMTU may be used to look up the MTU toward the destination.
+type MTUError struct {
+	MTU int   // largest datagram size, including the IPv4 header
+	Err error // underlying error
+}
+
+func (e *MTUError) Error() string { return e.Err.Error() + " (mtu " + strconv.Itoa(e.MTU) + ")" }
+
+// Unwrap returns the underlying error.
+func (e *MTUError) Unwrap() error { return e.Err }
+
+// EffectiveMTU returns the largest size of IPv4 datagrams, including
+// the IPv4 header, that can be sent to the destination dst without
+// fragmentation on the outgoing interface.  The outgoing interface is
+// determined by the routing table of the system, and the MTU of the
+// interface is clamped by the path MTU cached by the protocol stack,
+// if any, which is currently available only on Linux.  No packet is
+// sent to dst.
+//
+// It returns an error of type *net.OpError when no route to dst
+// exists.
+func EffectiveMTU(dst net.IP) (int, error) {
+	ip := dst.To4()
+	if ip == nil {
+		return 0, errNonIPv4Address
+	}
+	// Connecting a UDP endpoint performs the route lookup
+	// without any transmission.
+	c, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: ip, Port: 9})
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	src := c.LocalAddr().(*net.UDPAddr).IP
+	ifi, err := netIP4ToLocalInterface(src)
+	if err != nil {
+		return 0, err
+	}
+	mtu := ifi.MTU
+	o := genericOpt{Conn: c}
+	if fd, err := o.sysfd(); err == nil {
+		if pmtu, err := getInt(fd, &sockOpts[ssoPathMTU]); err == nil && pmtu > 0 && pmtu < mtu {
+			mtu = pmtu
+		}
+	}
+	return mtu, nil
+}
+
+// netIP4ToLocalInterface returns the network interface to which the
+// address ip is assigned.
+func netIP4ToLocalInterface(ip net.IP) (*net.Interface, error) {
+	ift, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ift {
+		ifat, err := ift[i].Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifa := range ifat {
+			switch ifa := ifa.(type) {
+			case *net.IPAddr:
+				if ip.Equal(ifa.IP) {
+					return &ift[i], nil
+				}
+			case *net.IPNet:
+				if ip.Equal(ifa.IP) {
+					return &ift[i], nil
+				}
+			}
+		}
+	}
+	return nil, errNoSuchInterface
+}
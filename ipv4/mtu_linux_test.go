@@ -0,0 +1,66 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+func TestPacketConnWriteToMTUError(t *testing.T) {
+	d, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer d.Close()
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	// The protocol stack knows the path MTU only for connected
+	// sockets.
+	rc, err := c.(*net.UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("net.UDPConn.SyscallConn failed: %v", err)
+	}
+	sa := &syscall.SockaddrInet4{Port: d.LocalAddr().(*net.UDPAddr).Port}
+	copy(sa.Addr[:], net.IPv4(127, 0, 0, 1).To4())
+	var serr error
+	if err := rc.Control(func(fd uintptr) { serr = syscall.Connect(int(fd), sa) }); err != nil {
+		t.Fatalf("syscall.RawConn.Control failed: %v", err)
+	}
+	if serr != nil {
+		t.Fatalf("syscall.Connect failed: %v", serr)
+	}
+
+	// A datagram exceeding the maximum IPv4 datagram size always
+	// fails with EMSGSIZE.
+	_, err = p.WriteTo(make([]byte, 1<<16), nil, d.LocalAddr())
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		t.Fatalf("got %#v; expected *net.OpError", err)
+	}
+	me, ok := oe.Err.(*ipv4.MTUError)
+	if !ok {
+		t.Fatalf("got %#v; expected *ipv4.MTUError", oe.Err)
+	}
+	if me.MTU <= 0 {
+		t.Fatalf("got mtu %v; expected positive value", me.MTU)
+	}
+	err = me.Unwrap()
+	if serr, ok := err.(*os.SyscallError); ok {
+		err = serr.Err
+	}
+	if err != syscall.EMSGSIZE {
+		t.Fatalf("got %v; expected %v", err, syscall.EMSGSIZE)
+	}
+}
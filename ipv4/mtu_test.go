@@ -0,0 +1,69 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/internal/nettest"
+	"golang.org/x/net/ipv4"
+)
+
+func TestEffectiveMTU(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	mtu, err := ipv4.EffectiveMTU(net.IPv4(127, 0, 0, 1))
+	if err != nil {
+		t.Fatalf("ipv4.EffectiveMTU failed: %v", err)
+	}
+	if mtu <= 0 || mtu > ifi.MTU {
+		t.Fatalf("got %v; expected 0 < mtu <= %v", mtu, ifi.MTU)
+	}
+	if _, err := ipv4.EffectiveMTU(net.ParseIP("2001:db8::1")); err == nil {
+		t.Fatal("ipv4.EffectiveMTU succeeded with IPv6 address")
+	}
+}
+
+func TestPacketConnProbe(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	// A closed port makes the destination return an ICMP port
+	// unreachable message, which ends the probe early.
+	cc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	dst := cc.LocalAddr()
+	cc.Close()
+
+	fits, mtu, err := p.Probe(dst, 1280)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.Probe failed: %v", err)
+	}
+	if !fits || mtu < 1280 {
+		t.Fatalf("got %v, %v; expected true, mtu >= 1280", fits, mtu)
+	}
+	if _, _, err := p.Probe(dst, ipv4.HeaderLen); err == nil {
+		t.Fatal("ipv4.PacketConn.Probe succeeded with size shorter than headers")
+	}
+}
@@ -265,3 +265,78 @@ func TestRawConnReadWriteMulticastICMP(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketConnWriteMulticastUDPWithSrc(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	var ifi *net.Interface
+	var srcs []net.IP
+	ift, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("net.Interfaces failed: %v", err)
+	}
+	for i := range ift {
+		if ift[i].Flags&(net.FlagUp|net.FlagMulticast) != net.FlagUp|net.FlagMulticast {
+			continue
+		}
+		ifat, err := ift[i].Addrs()
+		if err != nil {
+			t.Fatalf("net.Interface.Addrs failed: %v", err)
+		}
+		srcs = srcs[:0]
+		for _, ifa := range ifat {
+			if ifa, ok := ifa.(*net.IPNet); ok && ifa.IP.To4() != nil {
+				srcs = append(srcs, ifa.IP.To4())
+			}
+		}
+		if len(srcs) >= 2 {
+			ifi = &ift[i]
+			break
+		}
+	}
+	if ifi == nil {
+		t.Skip("no multicast interface with multiple IPv4 addresses")
+	}
+
+	c, err := net.ListenPacket("udp4", "224.0.0.0:0") // see RFC 4727
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	_, port, err := net.SplitHostPort(c.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.SplitHostPort failed: %v", err)
+	}
+	dst, err := net.ResolveUDPAddr("udp4", "224.0.0.254:"+port) // see RFC 4727
+	if err != nil {
+		t.Fatalf("net.ResolveUDPAddr failed: %v", err)
+	}
+
+	p := ipv4.NewPacketConn(c)
+	if err := p.JoinGroup(ifi, dst); err != nil {
+		t.Fatalf("ipv4.PacketConn.JoinGroup on %v failed: %v", ifi, err)
+	}
+	if err := p.SetMulticastInterface(ifi); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetMulticastInterface failed: %v", err)
+	}
+	if err := p.SetMulticastLoopback(true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetMulticastLoopback failed: %v", err)
+	}
+	for _, src := range srcs[:2] {
+		if err := p.SetDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+			t.Fatalf("ipv4.PacketConn.SetDeadline failed: %v", err)
+		}
+		if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), &ipv4.ControlMessage{Src: src}, dst); err != nil {
+			t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+		}
+		b := make([]byte, 128)
+		_, _, from, err := p.ReadFrom(b)
+		if err != nil {
+			t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+		}
+		if !from.(*net.UDPAddr).IP.Equal(src) {
+			t.Fatalf("got %v; expected %v", from, src)
+		}
+	}
+}
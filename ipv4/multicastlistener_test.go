@@ -248,3 +248,34 @@ func TestIPPerInterfaceSingleRawConnWithSingleGroupListener(t *testing.T) {
 		}
 	}
 }
+
+func TestListenLinkLocalMulticast(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagMulticast|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	if _, err := ipv4.ListenLinkLocalMulticast(ifi, &net.UDPAddr{IP: net.IPv4(239, 0, 0, 254), Port: 1024}); err == nil {
+		t.Fatal("ipv4.ListenLinkLocalMulticast succeeded with non-link-local group")
+	}
+	if _, err := ipv4.ListenLinkLocalMulticast(nil, &net.UDPAddr{IP: net.IPv4(224, 0, 0, 254), Port: 1024}); err == nil {
+		t.Fatal("ipv4.ListenLinkLocalMulticast succeeded without interface")
+	}
+
+	p, err := ipv4.ListenLinkLocalMulticast(ifi, &net.UDPAddr{IP: net.IPv4(224, 0, 0, 254), Port: 1024})
+	if err != nil {
+		t.Fatalf("ipv4.ListenLinkLocalMulticast failed: %v", err)
+	}
+	defer p.Close()
+	mifi, err := p.MulticastInterface()
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.MulticastInterface failed: %v", err)
+	}
+	if mifi == nil || mifi.Index != ifi.Index {
+		t.Fatalf("got %v; expected %v", mifi, ifi)
+	}
+}
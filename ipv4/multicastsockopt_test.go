@@ -5,9 +5,11 @@
 package ipv4_test
 
 import (
+	"errors"
 	"net"
 	"os"
 	"runtime"
+	"sync"
 	"testing"
 
 	"golang.org/x/net/internal/nettest"
@@ -111,3 +113,147 @@ func testMulticastSocketOptions(t *testing.T, c testIPv4MulticastConn, ifi *net.
 		t.Fatalf("ipv4.PacketConn.LeaveGroup(%v, %v) failed: %v", ifi, gaddr, err)
 	}
 }
+
+func TestPacketConnConcurrentJoinLeaveGroup(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagMulticast|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "224.0.0.0:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	const N = 8
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			grp := &net.UDPAddr{IP: net.IPv4(239, 255, 0, byte(i+1))}
+			for j := 0; j < 10; j++ {
+				if err := p.JoinGroup(ifi, grp); err != nil {
+					t.Errorf("ipv4.PacketConn.JoinGroup(%v, %v) failed: %v", ifi, grp, err)
+					return
+				}
+				if err := p.LeaveGroup(ifi, grp); err != nil {
+					t.Errorf("ipv4.PacketConn.LeaveGroup(%v, %v) failed: %v", ifi, grp, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPacketConnCloseGraceful(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagMulticast|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "224.0.0.0:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	p := ipv4.NewPacketConn(c)
+
+	for i := 1; i <= 4; i++ {
+		grp := &net.UDPAddr{IP: net.IPv4(239, 255, 0, byte(i))}
+		if err := p.JoinGroup(ifi, grp); err != nil {
+			c.Close()
+			t.Fatalf("ipv4.PacketConn.JoinGroup(%v, %v) failed: %v", ifi, grp, err)
+		}
+	}
+	if err := p.LeaveGroup(ifi, &net.UDPAddr{IP: net.IPv4(239, 255, 0, 1)}); err != nil {
+		c.Close()
+		t.Fatalf("ipv4.PacketConn.LeaveGroup failed: %v", err)
+	}
+	if err := p.CloseGraceful(); err != nil {
+		t.Fatalf("ipv4.PacketConn.CloseGraceful failed: %v", err)
+	}
+}
+
+func TestPacketConnJoinGroupNotMulticast(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	grp := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1)}
+	for _, fn := range []func(*net.Interface, net.Addr) error{p.JoinGroup, p.LeaveGroup} {
+		err := fn(nil, grp)
+		if !errors.Is(err, ipv4.ErrNotMulticast) {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotMulticast)
+		}
+		if nerr, ok := err.(*ipv4.NotMulticastError); !ok || !nerr.Addr.Equal(grp.IP) {
+			t.Fatalf("got %#v; expected *ipv4.NotMulticastError carrying %v", err, grp.IP)
+		}
+	}
+}
+
+func TestPacketConnSetMulticastInterfaceAddr(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if err := p.SetMulticastInterfaceAddr(net.IPv4(192, 0, 2, 1)); err == nil {
+		t.Fatal("ipv4.PacketConn.SetMulticastInterfaceAddr succeeded with non-local address")
+	}
+	if err := p.SetMulticastInterfaceAddr(net.ParseIP("2001:db8::1")); err == nil {
+		t.Fatal("ipv4.PacketConn.SetMulticastInterfaceAddr succeeded with IPv6 address")
+	}
+	if err := p.SetMulticastInterfaceAddr(net.IPv4(127, 0, 0, 1)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetMulticastInterfaceAddr failed: %v", err)
+	}
+}
+
+func TestInterfaceMulticastGroups(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	grps, err := ipv4.InterfaceMulticastGroups(nil)
+	if err != nil {
+		t.Fatalf("ipv4.InterfaceMulticastGroups failed: %v", err)
+	}
+	for _, grp := range grps {
+		if grp.To4() == nil || !grp.IsMulticast() {
+			t.Fatalf("got %v; expected IPv4 multicast address", grp)
+		}
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagMulticast|net.FlagLoopback)
+	if ifi == nil {
+		return
+	}
+	if _, err := ipv4.InterfaceMulticastGroups(ifi); err != nil {
+		t.Fatalf("ipv4.InterfaceMulticastGroups failed: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import "net"
+
+// Options represents a set of socket options applied to an endpoint
+// by NewPacketConnWithOptions.  Zero values of the fields leave the
+// corresponding options untouched.
+type Options struct {
+	ReadBuffer         int            // receive buffer size in bytes
+	WriteBuffer        int            // send buffer size in bytes
+	TOS                int            // type-of-service field value for outgoing packets
+	TTL                int            // time-to-live field value for outgoing packets
+	MulticastTTL       int            // time-to-live field value for outgoing multicast packets
+	MulticastInterface *net.Interface // outgoing interface for multicast packets
+	MulticastLoopback  *bool          // loopback of outgoing multicast packets
+	ControlFlags       ControlFlags   // per packet IP-level socket options to turn on
+}
+
+// An OptionError reports the failure of setting a socket option.
+type OptionError struct {
+	Option string // name of the field of Options, such as "TTL"
+	Err    error  // error returned on setting the option
+}
+
+func (e *OptionError) Error() string { return "set " + e.Option + ": " + e.Err.Error() }
+
+// NewPacketConnWithOptions returns a new PacketConn using c as its
+// underlying transport, after applying opts to it in the order of the
+// fields of Options.  When an option fails, it stops applying the
+// remaining ones and returns the PacketConn along with an
+// *OptionError identifying the option; the endpoint is left open and
+// configured with the options applied before the failure.
+func NewPacketConnWithOptions(c net.PacketConn, opts Options) (*PacketConn, error) {
+	p := NewPacketConn(c)
+	fail := func(name string, err error) (*PacketConn, error) {
+		return p, &OptionError{Option: name, Err: err}
+	}
+	type bufferSetter interface {
+		SetReadBuffer(int) error
+		SetWriteBuffer(int) error
+	}
+	if opts.ReadBuffer != 0 || opts.WriteBuffer != 0 {
+		bc, ok := c.(bufferSetter)
+		if !ok {
+			if opts.ReadBuffer != 0 {
+				return fail("ReadBuffer", ErrInvalidConnType)
+			}
+			return fail("WriteBuffer", ErrInvalidConnType)
+		}
+		if opts.ReadBuffer != 0 {
+			if err := bc.SetReadBuffer(opts.ReadBuffer); err != nil {
+				return fail("ReadBuffer", err)
+			}
+		}
+		if opts.WriteBuffer != 0 {
+			if err := bc.SetWriteBuffer(opts.WriteBuffer); err != nil {
+				return fail("WriteBuffer", err)
+			}
+		}
+	}
+	if opts.TOS != 0 {
+		if err := p.SetTOS(opts.TOS); err != nil {
+			return fail("TOS", err)
+		}
+	}
+	if opts.TTL != 0 {
+		if err := p.SetTTL(opts.TTL); err != nil {
+			return fail("TTL", err)
+		}
+	}
+	if opts.MulticastTTL != 0 {
+		if err := p.SetMulticastTTL(opts.MulticastTTL); err != nil {
+			return fail("MulticastTTL", err)
+		}
+	}
+	if opts.MulticastInterface != nil {
+		if err := p.SetMulticastInterface(opts.MulticastInterface); err != nil {
+			return fail("MulticastInterface", err)
+		}
+	}
+	if opts.MulticastLoopback != nil {
+		if err := p.SetMulticastLoopback(*opts.MulticastLoopback); err != nil {
+			return fail("MulticastLoopback", err)
+		}
+	}
+	if opts.ControlFlags != 0 {
+		if err := p.SetControlMessage(opts.ControlFlags, true); err != nil {
+			return fail("ControlFlags", err)
+		}
+	}
+	return p, nil
+}
@@ -13,6 +13,8 @@ import (
 type packetHandler struct {
 	c *net.IPConn
 	rawOpt
+	verify bool   // verify header checksum on received datagrams
+	peer   net.IP // connected peer
 }
 
 func (c *packetHandler) ok() bool { return c != nil && c.c != nil }
@@ -25,31 +27,94 @@ func (c *packetHandler) ReadFrom(b []byte) (h *Header, p []byte, cm *ControlMess
 		return nil, nil, nil, syscall.EINVAL
 	}
 	oob := newControlMessage(&c.rawOpt)
-	n, oobn, _, src, err := c.c.ReadMsgIP(b, oob)
+	n, oobn, flags, src, err := c.c.ReadMsgIP(b, oob)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, sysError("recvmsg", err)
 	}
 	var hs []byte
 	if hs, p, err = slicePacket(b[:n]); err != nil {
 		return nil, nil, nil, err
 	}
+	c.rawOpt.RLock()
+	verify := c.verify
+	cpu := c.rawOpt.isset(FlagIncomingCPU)
+	c.rawOpt.RUnlock()
+	if verify && checksum(hs) != 0 {
+		return nil, nil, nil, ErrInvalidChecksum
+	}
 	if h, err = ParseHeader(hs); err != nil {
 		return nil, nil, nil, err
 	}
 	if cm, err = parseControlMessage(oob[:oobn]); err != nil {
 		return nil, nil, nil, err
 	}
-	if src != nil && cm != nil {
-		cm.Src = src.IP
+	if cpu {
+		if cm == nil {
+			cm = &ControlMessage{}
+		}
+		cm.IncomingCPU = c.incomingCPU()
+	}
+	if controlMessageTruncated(flags) {
+		if cm == nil {
+			cm = &ControlMessage{}
+		}
+		cm.Truncated = true
+	}
+	if cm != nil {
+		if src != nil {
+			cm.Src = src.IP
+		}
+		cm.ifnames = &c.rawOpt.ifnames
 	}
 	return
 }
 
+// ReadFromFiltered is like ReadFrom but keeps reading until accept
+// returns true for the header h and the payload p of a received
+// datagram, discarding the datagrams rejected.  It is useful to skip
+// the packets of no interest, such as the ICMP echo requests sent by
+// the endpoint itself, which are also delivered to the endpoint on
+// some platforms.
+//
+// The read deadline applies to the whole call, not to each read,
+// since the deadline is an absolute time.  The accept function is
+// not allowed to retain h or p, as b is reused for the next read.
+func (c *packetHandler) ReadFromFiltered(b []byte, accept func(h *Header, p []byte) bool) (h *Header, p []byte, cm *ControlMessage, err error) {
+	for {
+		if h, p, cm, err = c.ReadFrom(b); err != nil {
+			return nil, nil, nil, err
+		}
+		if accept(h, p) {
+			return
+		}
+	}
+}
+
+// incomingCPU returns the CPU on which the protocol stack processed
+// the most recent packet for the endpoint, or -1 if unknown.
+func (c *packetHandler) incomingCPU() int {
+	fd, err := c.sysfd()
+	if err != nil {
+		return -1
+	}
+	cpu, err := getInt(fd, &sockOpts[ssoIncomingCPU])
+	if err != nil {
+		return -1
+	}
+	return cpu
+}
+
 func slicePacket(b []byte) (h, p []byte, err error) {
 	if len(b) < HeaderLen {
-		return nil, nil, errHeaderTooShort
+		return nil, nil, ErrHeaderTooShort
 	}
 	hdrlen := int(b[0]&0x0f) << 2
+	if hdrlen < HeaderLen {
+		return nil, nil, ErrHeaderTooShort
+	}
+	if hdrlen > len(b) {
+		return nil, nil, errBufferTooShort
+	}
 	return b[:hdrlen], b[hdrlen:], nil
 }
 
@@ -73,25 +138,81 @@ func slicePacket(b []byte) (h, p []byte, err error) {
 //	Src           = platform sets an appropriate value if Src is nil
 //	Dst           = <must be specified>
 //	Options       = optional
+//
+// Since the endpoint always has the IP_HDRINCL socket option enabled,
+// a non-zero ID field of h is transmitted as is, which allows the
+// application to generate deterministic identification values, for
+// example for reproducible tests.  There is no portable way to control
+// the identification field of datagrams sent through PacketConn; the
+// protocol stack assigns it.
+//
+// The destination address of the datagram is taken from the Dst field
+// of cm if specified, otherwise from the Dst field of h.  Either must
+// be a 4-byte IPv4 address or a 16-byte IPv4-mapped IPv6 address; any
+// other address in the Dst field of cm is rejected with an error.
+// Errors reported by the underlying system call are returned as
+// *net.OpError; see the package documentation for details.
 func (c *packetHandler) WriteTo(h *Header, p []byte, cm *ControlMessage) error {
+	_, err := c.WriteToN(h, p, cm)
+	return err
+}
+
+// Write writes an IPv4 datagram through the endpoint c connected by
+// Connect, copying the datagram from the IPv4 header h and the
+// payload p.  The datagram is sent to the connected peer, and the Dst
+// field of h is filled in with the peer when unspecified.  It returns
+// ErrMissingAddress when the endpoint is not connected.
+func (c *packetHandler) Write(h *Header, p []byte) error {
 	if !c.ok() {
 		return syscall.EINVAL
 	}
-	oob := marshalControlMessage(cm)
+	c.rawOpt.RLock()
+	peer := c.peer
+	c.rawOpt.RUnlock()
+	if peer == nil {
+		return ErrMissingAddress
+	}
+	if h == nil {
+		return ErrMissingHeader
+	}
+	if h.Dst == nil {
+		nh := *h
+		nh.Dst = peer
+		h = &nh
+	}
 	wh, err := h.Marshal()
 	if err != nil {
 		return err
 	}
+	if _, err = c.c.Write(append(wh, p...)); err != nil {
+		return sysError("write", err)
+	}
+	return nil
+}
+
+// WriteToN is like WriteTo but also returns the number of bytes
+// written, including the IPv4 header.
+func (c *packetHandler) WriteToN(h *Header, p []byte, cm *ControlMessage) (n int, err error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	oob := marshalControlMessage(cm)
+	wh, err := h.Marshal()
+	if err != nil {
+		return 0, err
+	}
 	dst := &net.IPAddr{}
-	if cm != nil {
-		if ip := cm.Dst.To4(); ip != nil {
-			dst.IP = ip
+	if cm != nil && cm.Dst != nil {
+		if dst.IP = cm.Dst.To4(); dst.IP == nil {
+			return 0, errNonIPv4Address
 		}
 	}
 	if dst.IP == nil {
-		dst.IP = h.Dst
+		dst.IP = h.Dst.To4()
 	}
 	wh = append(wh, p...)
-	_, _, err = c.c.WriteMsgIP(wh, oob, dst)
-	return err
+	if n, _, err = c.c.WriteMsgIP(wh, oob, dst); err != nil {
+		return 0, sysError("sendmsg", err)
+	}
+	return
 }
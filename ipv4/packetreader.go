@@ -0,0 +1,66 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"io"
+)
+
+var errInvalidFrameLen = errors.New("invalid frame length")
+
+// A PacketReader reads IPv4 packets from a stream, such as a file
+// holding packets saved for offline analysis.
+//
+// The stream consists of frames, each of which is a 4-octet length
+// field in network byte order followed by an IPv4 packet of that
+// length in wire format, beginning with the IPv4 header.  The length
+// must not be greater than 65535.
+type PacketReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewPacketReader returns a new PacketReader reading from r.
+func NewPacketReader(r io.Reader) *PacketReader {
+	return &PacketReader{r: r}
+}
+
+// Next reads the next packet from the stream and returns its IPv4
+// header h and payload p.  The payload is truncated to the length
+// given by the total length field of the header when the packet is
+// longer.  It returns io.EOF when the stream ends at a frame
+// boundary, and io.ErrUnexpectedEOF when it ends in the middle of a
+// frame.
+//
+// The returned payload refers to the buffer of the PacketReader and
+// is valid only until the next call to Next.
+func (pr *PacketReader) Next() (h *Header, p []byte, err error) {
+	var l [4]byte
+	if _, err := io.ReadFull(pr.r, l[:]); err != nil {
+		return nil, nil, err
+	}
+	n := int(l[0])<<24 | int(l[1])<<16 | int(l[2])<<8 | int(l[3])
+	if n < 0 || n > 0xffff {
+		return nil, nil, errInvalidFrameLen
+	}
+	if cap(pr.buf) < n {
+		pr.buf = make([]byte, n)
+	}
+	b := pr.buf[:n]
+	if _, err := io.ReadFull(pr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	if h, err = parseHeader(b, false); err != nil {
+		return nil, nil, err
+	}
+	if h.TotalLen < len(b) {
+		b = b[:h.TotalLen]
+	}
+	return h, b[h.Len:], nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+func frame(b []byte) []byte {
+	l := len(b)
+	return append([]byte{byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)}, b...)
+}
+
+func TestPacketReader(t *testing.T) {
+	pkt := []byte{
+		0x45, 0x00, 0x00, 0x1b, 0xca, 0xfe, 0x40, 0x00,
+		0x40, 0x11, 0x00, 0x00, 192, 0, 2, 1,
+		198, 51, 100, 1,
+		'H', 'E', 'L', 'L', 'O', '-', 'R',
+	}
+	var buf bytes.Buffer
+	buf.Write(frame(pkt))
+	buf.Write(frame(append(pkt, 0, 0, 0))) // trailing padding
+	r := ipv4.NewPacketReader(&buf)
+	for i := 0; i < 2; i++ {
+		h, p, err := r.Next()
+		if err != nil {
+			t.Fatalf("ipv4.PacketReader.Next failed: %v", err)
+		}
+		if h.TotalLen != 27 || h.ID != 0xcafe || h.Flags != ipv4.DontFragment || h.TTL != 64 || h.Protocol != 17 || !h.Src.Equal(net.IPv4(192, 0, 2, 1)) || !h.Dst.Equal(net.IPv4(198, 51, 100, 1)) {
+			t.Fatalf("got unexpected header %v", h)
+		}
+		if string(p) != "HELLO-R" {
+			t.Fatalf("got %q; expected %q", p, "HELLO-R")
+		}
+	}
+	if _, _, err := r.Next(); err != io.EOF {
+		t.Fatalf("got %v; expected %v", err, io.EOF)
+	}
+
+	r = ipv4.NewPacketReader(bytes.NewReader(frame(pkt)[:10]))
+	if _, _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v; expected %v", err, io.ErrUnexpectedEOF)
+	}
+	r = ipv4.NewPacketReader(bytes.NewReader(frame(pkt[:ipv4.HeaderLen-1])))
+	if _, _, err := r.Next(); err != ipv4.ErrHeaderTooShort {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrHeaderTooShort)
+	}
+	r = ipv4.NewPacketReader(bytes.NewReader([]byte{0, 1, 0, 0}))
+	if _, _, err := r.Next(); err == nil {
+		t.Fatal("ipv4.PacketReader.Next succeeded with oversized frame")
+	}
+}
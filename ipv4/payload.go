@@ -13,3 +13,24 @@ type payloadHandler struct {
 }
 
 func (c *payloadHandler) ok() bool { return c != nil && c.PacketConn != nil }
+
+// ReadFromFiltered is like ReadFrom but keeps reading until accept
+// returns true for the number of bytes n copied into b, the control
+// message cm and the source address src of a received datagram,
+// discarding the datagrams rejected.  It is useful to skip the
+// replies of no interest, such as ICMP echo replies carrying an
+// unexpected identifier or sequence number.
+//
+// The read deadline applies to the whole call, not to each read,
+// since the deadline is an absolute time.  The accept function is
+// not allowed to retain b, as it is reused for the next read.
+func (c *payloadHandler) ReadFromFiltered(b []byte, accept func(n int, cm *ControlMessage, src net.Addr) bool) (n int, cm *ControlMessage, src net.Addr, err error) {
+	for {
+		if n, cm, src, err = c.ReadFrom(b); err != nil {
+			return 0, nil, nil, err
+		}
+		if accept(n, cm, src) {
+			return
+		}
+	}
+}
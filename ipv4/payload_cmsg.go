@@ -8,7 +8,11 @@ package ipv4
 
 import (
 	"net"
+	"os"
 	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
 // ReadFrom reads a payload of the received IPv4 datagram, from the
@@ -19,57 +23,354 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 	if !c.ok() {
 		return 0, nil, nil, syscall.EINVAL
 	}
-	oob := newControlMessage(&c.rawOpt)
-	var oobn int
-	switch c := c.PacketConn.(type) {
+	n, cm, src, _, err = c.recvFrom(b, 0)
+	return
+}
+
+// incomingCPU returns the CPU on which the protocol stack processed
+// the most recent packet for the endpoint, or -1 if unknown.
+func (c *payloadHandler) incomingCPU() int {
+	fd, err := c.sysfd()
+	if err != nil {
+		return -1
+	}
+	cpu, err := getInt(fd, &sockOpts[ssoIncomingCPU])
+	if err != nil {
+		return -1
+	}
+	return cpu
+}
+
+// TryReadFrom is like ReadFrom but never blocks.  It reports ok as
+// false with a nil error when no datagram is ready to be read, which
+// is useful for integrating the endpoint with an external event loop.
+// It isn't subject to the read deadline.
+func (c *payloadHandler) TryReadFrom(b []byte) (n int, cm *ControlMessage, src net.Addr, ok bool, err error) {
+	if !c.ok() {
+		return 0, nil, nil, false, syscall.EINVAL
+	}
+	return c.recvFrom(b, syscall.MSG_DONTWAIT)
+}
+
+// recvFrom receives a datagram with the receive flags flags and
+// returns its payload copied into b, along with the control message
+// and the source address.  It waits on the runtime network poller
+// until a datagram arrives, subject to the read deadline, unless
+// flags contains MSG_DONTWAIT, in which case it reports ok as false
+// when no datagram is ready.
+func (c *payloadHandler) recvFrom(b []byte, flags int) (n int, cm *ControlMessage, src net.Addr, ok bool, err error) {
+	pc := c.conn()
+	var isIP bool
+	switch pc.(type) {
 	case *net.UDPConn:
-		if n, oobn, _, src, err = c.ReadMsgUDP(b, oob); err != nil {
-			return 0, nil, nil, err
-		}
 	case *net.IPConn:
-		nb := make([]byte, maxHeaderLen+len(b))
-		if n, oobn, _, src, err = c.ReadMsgIP(nb, oob); err != nil {
-			return 0, nil, nil, err
-		}
-		hdrlen := int(nb[0]&0x0f) << 2
-		copy(b, nb[hdrlen:])
-		n -= hdrlen
+		isIP = true
 	default:
-		return 0, nil, nil, errInvalidConnType
+		return 0, nil, nil, false, ErrInvalidConnType
+	}
+	rc, err := rawConn(pc)
+	if err != nil {
+		return 0, nil, nil, false, err
+	}
+	oob := newControlMessage(&c.rawOpt)
+	c.rawOpt.RLock()
+	raw := c.rawOpt.isset(FlagRawHeader)
+	cpu := c.rawOpt.isset(FlagIncomingCPU)
+	c.rawOpt.RUnlock()
+	rb := b
+	if isIP {
+		rb = make([]byte, maxHeaderLen+len(b))
+	}
+	var oobn, rflags int
+	var from syscall.Sockaddr
+	var serr error
+	recv := func(fd uintptr) {
+		n, oobn, rflags, from, serr = syscall.Recvmsg(int(fd), rb, oob, flags|syscall.MSG_DONTWAIT)
+	}
+	if flags&syscall.MSG_DONTWAIT != 0 {
+		// Control doesn't consult the read deadline.
+		err = rc.Control(recv)
+	} else {
+		err = rc.Read(func(fd uintptr) bool {
+			recv(fd)
+			return serr != syscall.EAGAIN
+		})
 	}
+	if err != nil {
+		return 0, nil, nil, false, rawOpError("read", err)
+	}
+	if serr == syscall.EAGAIN {
+		return 0, nil, nil, false, nil
+	}
+	if serr != nil {
+		return 0, nil, nil, false, &net.OpError{Op: "read", Net: pc.LocalAddr().Network(), Addr: pc.LocalAddr(), Err: os.NewSyscallError("recvmsg", serr)}
+	}
+	var h *Header
+	if isIP {
+		hs, p, err := slicePacket(rb[:n])
+		if err != nil {
+			return 0, nil, nil, false, err
+		}
+		n = copy(b, p)
+		if raw {
+			if h, err = ParseHeader(hs); err != nil {
+				return 0, nil, nil, false, err
+			}
+		}
+	}
+	src = sockaddrToNetAddr(from, isIP)
 	if cm, err = parseControlMessage(oob[:oobn]); err != nil {
-		return 0, nil, nil, err
+		return 0, nil, nil, false, err
+	}
+	if h != nil || cpu {
+		if cm == nil {
+			cm = &ControlMessage{}
+		}
+		cm.Header = h
+	}
+	if cpu {
+		cm.IncomingCPU = c.incomingCPU()
+	}
+	if controlMessageTruncated(rflags) {
+		if cm == nil {
+			cm = &ControlMessage{}
+		}
+		cm.Truncated = true
 	}
 	if cm != nil {
 		cm.Src = netAddrToIP4(src)
+		cm.ifnames = &c.rawOpt.ifnames
 	}
-	return
+	return n, cm, src, true, nil
+}
+
+// PeekFrom reads a payload of the next IPv4 datagram, from the
+// endpoint c, copying the payload into b, without removing the
+// datagram from the receive queue; a subsequent ReadFrom returns the
+// same datagram.  It returns the number of bytes copied into b and
+// the source address src of the datagram.
+//
+// The payload is truncated when it is longer than b.  Control
+// messages are never peeked.  Like ReadFrom, it blocks until a
+// datagram arrives and is subject to the read deadline.
+func (c *payloadHandler) PeekFrom(b []byte) (n int, src net.Addr, err error) {
+	if !c.ok() {
+		return 0, nil, syscall.EINVAL
+	}
+	pc := c.conn()
+	var isIP bool
+	switch pc.(type) {
+	case *net.UDPConn:
+	case *net.IPConn:
+		isIP = true
+	default:
+		return 0, nil, ErrInvalidConnType
+	}
+	rc, err := rawConn(pc)
+	if err != nil {
+		return 0, nil, err
+	}
+	rb := b
+	if isIP {
+		rb = make([]byte, maxHeaderLen+len(b))
+	}
+	var from syscall.Sockaddr
+	var serr error
+	err = rc.Read(func(fd uintptr) bool {
+		n, _, _, from, serr = syscall.Recvmsg(int(fd), rb, nil, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		return serr != syscall.EAGAIN
+	})
+	if err != nil {
+		return 0, nil, rawOpError("read", err)
+	}
+	if serr != nil {
+		return 0, nil, &net.OpError{Op: "read", Net: pc.LocalAddr().Network(), Err: os.NewSyscallError("recvmsg", serr)}
+	}
+	src = sockaddrToNetAddr(from, isIP)
+	if isIP {
+		_, p, err := slicePacket(rb[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+		n = copy(b, p)
+	}
+	return n, src, nil
+}
+
+func sockaddrToNetAddr(sa syscall.Sockaddr, isIP bool) net.Addr {
+	sa4, ok := sa.(*syscall.SockaddrInet4)
+	if !ok {
+		return nil
+	}
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, sa4.Addr[:])
+	if isIP {
+		return &net.IPAddr{IP: ip}
+	}
+	return &net.UDPAddr{IP: ip, Port: sa4.Port}
+}
+
+// rawConn returns the syscall.RawConn of the transport pc, through
+// which the endpoint waits on the runtime network poller.
+func rawConn(pc net.PacketConn) (syscall.RawConn, error) {
+	sc, ok := pc.(syscall.Conn)
+	if !ok {
+		return nil, ErrInvalidConnType
+	}
+	return sc.SyscallConn()
+}
+
+// rawOpError returns err, an error returned by the methods of
+// syscall.RawConn, with the operation reported as op instead of the
+// raw one.
+func rawOpError(op string, err error) error {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		return err
+	}
+	noe := *oe
+	noe.Op = op
+	return &noe
+}
+
+// WaitReadable blocks until a datagram is ready to be read from the
+// endpoint c, or ctx is done, in which case it returns ctx.Err().  It
+// doesn't consume the datagram; a subsequent ReadFrom or TryReadFrom
+// returns it.  Together with TryReadFrom, it allows an application to
+// decide which of many endpoints to service before committing a
+// buffer.
+//
+// The wait is made on the runtime network poller and is subject to
+// the read deadline, which it reports as a timeout error.  To stop
+// the wait when ctx is done, WaitReadable sets the read deadline to
+// a time in the past, in which case it always returns ctx.Err(); the
+// caller needs to reset the deadline before reading from the endpoint
+// again.  The deadline is left alone once WaitReadable returns.
+func (c *payloadHandler) WaitReadable(ctx context.Context) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	pc := c.conn()
+	switch pc.(type) {
+	case *net.UDPConn, *net.IPConn:
+	default:
+		return ErrInvalidConnType
+	}
+	rc, err := rawConn(pc)
+	if err != nil {
+		return err
+	}
+	w := watchContext(ctx, func() { pc.SetReadDeadline(time.Unix(1, 0)) })
+	var b [1]byte
+	var serr error
+	err = rc.Read(func(fd uintptr) bool {
+		_, _, _, _, serr = syscall.Recvmsg(int(fd), b[:], nil, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		return serr != syscall.EAGAIN
+	})
+	if w.stop() {
+		return ctx.Err()
+	}
+	if err != nil {
+		return rawOpError("read", err)
+	}
+	if serr != nil {
+		return &net.OpError{Op: "read", Net: pc.LocalAddr().Network(), Err: os.NewSyscallError("recvmsg", serr)}
+	}
+	return nil
 }
 
 // WriteTo writes a payload of the IPv4 datagram, to the destination
 // address dst through the endpoint c, copying the payload from b.  It
 // returns the number of bytes written.  The control message cm allows
 // the datagram path and the outgoing interface to be specified.
-// Currently only Darwin and Darwin support this.  The cm may be nil if
-// control of the outgoing datagram is not required.
+// Currently only Darwin and Linux support this, and FreeBSD supports
+// only the source address, through the IP_SENDSRCADDR option, since
+// it provides no way to specify the outgoing interface per datagram.
+// The cm may be nil if control of the outgoing datagram is not
+// required.
+//
+// The destination address dst must be *net.UDPAddr for UDP endpoints
+// or *net.IPAddr for IP endpoints, and must carry either a 4-byte
+// IPv4 address or a 16-byte IPv4-mapped IPv6 address such as
+// ::ffff:192.0.2.1, which is converted to its 4-byte form before
+// transmission.  Any other IPv6 address is rejected with an error.
+// Errors reported by the underlying system call are returned as
+// *net.OpError; see the package documentation for details.  When the
+// datagram is too large to be sent, the Err field of the error holds
+// *MTUError carrying the path MTU if the protocol stack knows it.
+//
+// On Linux, the Src field of cm pins the source address of the
+// datagram, which is passed to the protocol stack as the ipi_spec_dst
+// field of IP_PKTINFO.  It is honored for multicast destinations as
+// well, in place of the address the protocol stack picks on the
+// multicast interface, as long as Src is assigned to the host.
 func (c *payloadHandler) WriteTo(b []byte, cm *ControlMessage, dst net.Addr) (n int, err error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
 	}
 	oob := marshalControlMessage(cm)
+	c.rawOpt.RLock()
+	if cm != nil && !cm.TxTime.IsZero() && c.rawOpt.txtime {
+		oob = appendTxTime(oob, cm.TxTime)
+	}
+	if c.rawOpt.gsoSize > 0 {
+		oob = appendGSO(oob, c.rawOpt.gsoSize)
+	}
+	c.rawOpt.RUnlock()
+	if cm != nil && cm.Mark != 0 {
+		oob = appendMark(oob, cm.Mark)
+	}
 	if dst == nil {
-		return 0, errMissingAddress
+		return 0, ErrMissingAddress
+	}
+	if dst, err = netAddrToNetAddr4(dst); err != nil {
+		return 0, err
+	}
+	if cm != nil && cm.MulticastTTL > 0 {
+		if ip := netAddrToIP4(dst); ip != nil && ip.IsMulticast() {
+			oob = appendMulticastTTL(oob, cm.MulticastTTL)
+		}
 	}
-	switch c := c.PacketConn.(type) {
+	switch c := c.conn().(type) {
 	case *net.UDPConn:
 		n, _, err = c.WriteMsgUDP(b, oob, dst.(*net.UDPAddr))
 	case *net.IPConn:
 		n, _, err = c.WriteMsgIP(b, oob, dst.(*net.IPAddr))
 	default:
-		return 0, errInvalidConnType
+		return 0, ErrInvalidConnType
 	}
 	if err != nil {
-		return 0, err
+		return 0, c.mtuError(sysError("sendmsg", err))
 	}
 	return
 }
+
+// mtuError returns err with its Err field wrapped in *MTUError when
+// err reports that the datagram was too large, and the protocol stack
+// knows the path MTU of the endpoint.  Otherwise it returns err as
+// is.
+func (c *payloadHandler) mtuError(err error) error {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		return err
+	}
+	errno := oe.Err
+	if se, ok := errno.(*os.SyscallError); ok {
+		errno = se.Err
+	}
+	if errno != syscall.EMSGSIZE {
+		return err
+	}
+	fd, serr := c.sysfd()
+	if serr != nil {
+		return err
+	}
+	// The path MTU is available only for connected endpoints.
+	mtu, serr := getInt(fd, &sockOpts[ssoPathMTU])
+	if serr != nil || mtu <= 0 {
+		return err
+	}
+	noe := *oe
+	noe.Err = &MTUError{MTU: mtu, Err: oe.Err}
+	return &noe
+}
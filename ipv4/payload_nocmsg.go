@@ -9,6 +9,8 @@ package ipv4
 import (
 	"net"
 	"syscall"
+
+	"golang.org/x/net/context"
 )
 
 // ReadFrom reads a payload of the received IPv4 datagram, from the
@@ -20,23 +22,57 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 		return 0, nil, nil, syscall.EINVAL
 	}
 	if n, src, err = c.PacketConn.ReadFrom(b); err != nil {
-		return 0, nil, nil, err
+		return 0, nil, nil, sysError("recvfrom", err)
 	}
 	return
 }
 
+// TryReadFrom is like ReadFrom but never blocks.
+// It is not supported on this platform.
+func (c *payloadHandler) TryReadFrom(b []byte) (n int, cm *ControlMessage, src net.Addr, ok bool, err error) {
+	return 0, nil, nil, false, ErrNotSupported
+}
+
+// PeekFrom reads a payload of the next IPv4 datagram, from the
+// endpoint c, without removing the datagram from the receive queue.
+// It is not supported on this platform.
+func (c *payloadHandler) PeekFrom(b []byte) (n int, src net.Addr, err error) {
+	return 0, nil, ErrNotSupported
+}
+
+// WaitReadable blocks until a datagram is ready to be read from the
+// endpoint c.
+// It is not supported on this platform.
+func (c *payloadHandler) WaitReadable(ctx context.Context) error {
+	return ErrNotSupported
+}
+
 // WriteTo writes a payload of the IPv4 datagram, to the destination
 // address dst through the endpoint c, copying the payload from b.  It
 // returns the number of bytes written.  The control message cm allows
 // the datagram path and the outgoing interface to be specified.
 // Currently only Darwin and Linux support this.  The cm may be nil if
 // control of the outgoing datagram is not required.
+//
+// The destination address dst must be *net.UDPAddr for UDP endpoints
+// or *net.IPAddr for IP endpoints, and must carry either a 4-byte
+// IPv4 address or a 16-byte IPv4-mapped IPv6 address such as
+// ::ffff:192.0.2.1, which is converted to its 4-byte form before
+// transmission.  Any other IPv6 address is rejected with an error.
+// Errors reported by the underlying system call are returned as
+// *net.OpError; see the package documentation for details.
 func (c *payloadHandler) WriteTo(b []byte, cm *ControlMessage, dst net.Addr) (n int, err error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
 	}
 	if dst == nil {
-		return 0, errMissingAddress
+		return 0, ErrMissingAddress
+	}
+	if dst, err = netAddrToNetAddr4(dst); err != nil {
+		return 0, err
 	}
-	return c.PacketConn.WriteTo(b, dst)
+	if n, err = c.PacketConn.WriteTo(b, dst); err != nil {
+		return 0, sysError("sendto", err)
+	}
+	return
 }
@@ -20,7 +20,7 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 		return 0, nil, nil, syscall.EINVAL
 	}
 	if n, src, err = c.PacketConn.ReadFrom(b); err != nil {
-		return 0, nil, nil, err
+		return 0, nil, nil, sysError("recvfrom", err)
 	}
 	return
 }
@@ -31,12 +31,26 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 // the datagram path and the outgoing interface to be specified.
 // Currently only Darwin and Linux support this.  The cm may be nil if
 // control of the outgoing datagram is not required.
+//
+// The destination address dst must be *net.UDPAddr for UDP endpoints
+// or *net.IPAddr for IP endpoints, and must carry either a 4-byte
+// IPv4 address or a 16-byte IPv4-mapped IPv6 address such as
+// ::ffff:192.0.2.1, which is converted to its 4-byte form before
+// transmission.  Any other IPv6 address is rejected with an error.
+// Errors reported by the underlying system call are returned as
+// *net.OpError; see the package documentation for details.
 func (c *payloadHandler) WriteTo(b []byte, cm *ControlMessage, dst net.Addr) (n int, err error) {
 	if !c.ok() {
 		return 0, syscall.EINVAL
 	}
 	if dst == nil {
-		return 0, errMissingAddress
+		return 0, ErrMissingAddress
 	}
-	return c.PacketConn.WriteTo(b, dst)
+	if dst, err = netAddrToNetAddr4(dst); err != nil {
+		return 0, err
+	}
+	if n, err = c.PacketConn.WriteTo(b, dst); err != nil {
+		return 0, sysError("sendto", err)
+	}
+	return
 }
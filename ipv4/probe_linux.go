@@ -0,0 +1,145 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// probeWait is the duration for which Probe waits for an ICMP
+// fragmentation needed message after sending the probe.
+const probeWait = time.Second
+
+// Probe reports whether an IPv4 datagram of size octets, including
+// the IPv4 header and the UDP header, reaches the destination dst
+// without fragmentation.  It sends a UDP probe datagram filled with
+// zeros to dst with the don't fragment flag set, and reports fits as
+// false when the protocol stack rejects the datagram as too large
+// for the outgoing interface or the cached path MTU, or when a router
+// on the path returns an ICMP fragmentation needed message.  The mtu
+// is the path MTU toward dst known after the probe, which is the
+// next-hop MTU reported by the router if any.
+//
+// The probe is sent from a private UDP socket bound to the local
+// address of the endpoint and connected to dst, to the port of dst
+// when dst is *net.UDPAddr or to the discard port otherwise, so that
+// neither the socket options nor the error queue of the endpoint are
+// touched, and the endpoint remains usable by other goroutines
+// during the call.  Note that the options of the endpoint, such as
+// the TOS field or the outgoing interface, don't apply to the probe.
+//
+// Probe blocks for up to one second waiting for the ICMP message
+// after sending the probe, since the absence of the message is the
+// only indication that the probe fits, unless dst returns an ICMP
+// port unreachable message for the probe; a lost probe or an ICMP
+// message filtered on the path is indistinguishable from a probe
+// that fits.  Note that dst receives the probe when it fits.
+// Currently only Linux supports this.
+func (c *PacketConn) Probe(dst net.Addr, size int) (fits bool, mtu int, err error) {
+	if !c.payloadHandler.ok() {
+		return false, 0, syscall.EINVAL
+	}
+	raddr := &net.UDPAddr{IP: netAddrToIP4(dst), Port: 9}
+	if raddr.IP == nil {
+		return false, 0, ErrMissingAddress
+	}
+	if a, ok := dst.(*net.UDPAddr); ok {
+		raddr.Port = a.Port
+	}
+	const hdrlen = HeaderLen + 8
+	if size < hdrlen || size > 0xffff {
+		return false, 0, syscall.EINVAL
+	}
+	laddr := &net.UDPAddr{IP: netAddrToIP4(c.payloadHandler.LocalAddr())}
+	pc, err := net.DialUDP("udp4", laddr, raddr)
+	if err != nil {
+		return false, 0, err
+	}
+	defer pc.Close()
+	rc, err := pc.SyscallConn()
+	if err != nil {
+		return false, 0, err
+	}
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		if serr = setInt(int(fd), &sockOpts[ssoMTUDiscover], sysIP_PMTUDISC_DO); serr == nil {
+			serr = setInt(int(fd), &sockOpts[ssoReceiveErr], 1)
+		}
+	}); err != nil {
+		return false, 0, err
+	}
+	if serr != nil {
+		return false, 0, serr
+	}
+
+	if _, err := pc.Write(make([]byte, size-hdrlen)); err != nil {
+		if !isMsgSize(err) {
+			return false, 0, err
+		}
+		return false, probePathMTU(rc), nil
+	}
+	if err := pc.SetReadDeadline(time.Now().Add(probeWait)); err != nil {
+		return false, 0, err
+	}
+	oob := make([]byte, syscall.CmsgSpace(sysSizeofSockExtendedErr+syscall.SizeofSockaddrInet4))
+	for {
+		var oobn int
+		err := rc.Read(func(fd uintptr) bool {
+			_, oobn, _, _, serr = syscall.Recvmsg(int(fd), nil, oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+			return serr != syscall.EAGAIN
+		})
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			break
+		}
+		if err != nil {
+			return false, 0, rawOpError("read", err)
+		}
+		if serr != nil {
+			return false, 0, &net.OpError{Op: "read", Net: "udp4", Err: os.NewSyscallError("recvmsg", serr)}
+		}
+		ee, err := parseExtendedErr(oob[:oobn])
+		if err != nil {
+			continue
+		}
+		if ee.Err == syscall.EMSGSIZE {
+			return false, ee.Info, nil
+		}
+		// A port unreachable message comes from dst, which
+		// means that the probe reached it.
+		if ee.Origin == ExtendedErrOriginICMP && ee.Type == int(ICMPTypeDestinationUnreachable) && ee.Code == 3 {
+			break
+		}
+	}
+	return true, probePathMTU(rc), nil
+}
+
+// isMsgSize reports whether err, an error returned by net.Conn,
+// carries syscall.EMSGSIZE.
+func isMsgSize(err error) bool {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	err = oe.Err
+	if se, ok := err.(*os.SyscallError); ok {
+		err = se.Err
+	}
+	return err == syscall.EMSGSIZE
+}
+
+// probePathMTU returns the path MTU of the connected probe socket, or
+// zero if unknown.
+func probePathMTU(rc syscall.RawConn) int {
+	var mtu int
+	rc.Control(func(fd uintptr) {
+		if v, err := getInt(int(fd), &sockOpts[ssoPathMTU]); err == nil {
+			mtu = v
+		}
+	})
+	return mtu
+}
@@ -0,0 +1,16 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+import "net"
+
+// Probe reports whether an IPv4 datagram of size octets reaches the
+// destination dst without fragmentation.
+// Currently only Linux supports this.
+func (c *PacketConn) Probe(dst net.Addr, size int) (fits bool, mtu int, err error) {
+	return false, 0, ErrNotSupported
+}
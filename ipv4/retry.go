@@ -0,0 +1,86 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// A RetryPolicy represents the backoff policy used by WriteToRetry.
+//
+// The delay before the first retry is InitialDelay, or 1 millisecond
+// when InitialDelay is zero, and doubles for each retry up to
+// MaxDelay, or 100 milliseconds when MaxDelay is zero.  MaxRetries
+// limits the number of retries; zero means no limit, in which case
+// WriteToRetry keeps retrying until the write deadline of the
+// endpoint expires, or indefinitely when no deadline is set.
+type RetryPolicy struct {
+	MaxRetries   int           // maximum number of retries
+	InitialDelay time.Duration // delay before the first retry
+	MaxDelay     time.Duration // upper bound of the delay
+}
+
+const (
+	defaultRetryInitialDelay = time.Millisecond
+	defaultRetryMaxDelay     = 100 * time.Millisecond
+)
+
+// WriteToRetry is like WriteTo but retries the write while it fails
+// with a transient error, ENOBUFS or EAGAIN, waiting between the
+// attempts as specified by policy.  It is useful for sending bursts
+// of datagrams, such as multicast announcements, on platforms where
+// the protocol stack reports a temporary shortage of the interface
+// queue as ENOBUFS instead of blocking the sender, notably Darwin and
+// the BSD variants.
+//
+// Any other error, including a timeout error once the write deadline
+// of the endpoint expires, is returned immediately, as is the last
+// transient error when the retries are exhausted.
+func (c *PacketConn) WriteToRetry(b []byte, cm *ControlMessage, dst net.Addr, policy RetryPolicy) (int, error) {
+	if !c.payloadHandler.ok() || policy.MaxRetries < 0 || policy.InitialDelay < 0 || policy.MaxDelay < 0 {
+		return 0, syscall.EINVAL
+	}
+	return writeRetry(func() (int, error) { return c.WriteTo(b, cm, dst) }, policy, time.Sleep)
+}
+
+// writeRetry calls write until it succeeds, fails with an error that
+// isn't transient or the retries specified by policy are exhausted,
+// calling sleep with the delay between the attempts.
+func writeRetry(write func() (int, error), policy RetryPolicy, sleep func(time.Duration)) (int, error) {
+	d := policy.InitialDelay
+	if d == 0 {
+		d = defaultRetryInitialDelay
+	}
+	max := policy.MaxDelay
+	if max == 0 {
+		max = defaultRetryMaxDelay
+	}
+	for i := 0; ; i++ {
+		n, err := write()
+		if err == nil || !isTransientWriteError(err) || policy.MaxRetries > 0 && i >= policy.MaxRetries {
+			return n, err
+		}
+		if d > max {
+			d = max
+		}
+		sleep(d)
+		d *= 2
+	}
+}
+
+// isTransientWriteError reports whether err returned by WriteTo is
+// worth retrying.
+func isTransientWriteError(err error) bool {
+	if oe, ok := err.(*net.OpError); ok {
+		err = oe.Err
+	}
+	if se, ok := err.(*os.SyscallError); ok {
+		err = se.Err
+	}
+	return isTransientErrno(err)
+}
@@ -0,0 +1,13 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9
+
+package ipv4
+
+import "syscall"
+
+func isTransientErrno(err error) bool {
+	return err == syscall.ENOBUFS || err == syscall.EAGAIN
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var isTransientWriteErrorTests = []struct {
+	err error
+	ok  bool
+}{
+	{syscall.ENOBUFS, true},
+	{syscall.EAGAIN, true},
+	{os.NewSyscallError("sendmsg", syscall.ENOBUFS), true},
+	{&net.OpError{Op: "write", Net: "udp4", Err: os.NewSyscallError("sendmsg", syscall.ENOBUFS)}, true},
+	{&net.OpError{Op: "write", Net: "udp4", Err: os.NewSyscallError("sendmsg", syscall.EAGAIN)}, true},
+	{&net.OpError{Op: "write", Net: "udp4", Err: syscall.EAGAIN}, true},
+
+	{&net.OpError{Op: "write", Net: "udp4", Err: os.NewSyscallError("sendmsg", syscall.EHOSTUNREACH)}, false},
+	{syscall.EINVAL, false},
+	{errors.New("ENOBUFS"), false},
+	{nil, false},
+}
+
+func TestIsTransientWriteError(t *testing.T) {
+	for _, tt := range isTransientWriteErrorTests {
+		if ok := isTransientWriteError(tt.err); ok != tt.ok {
+			t.Errorf("isTransientWriteError(%v) = %v; expected %v", tt.err, ok, tt.ok)
+		}
+	}
+}
+
+var writeRetryTests = []struct {
+	policy RetryPolicy
+	errs   []error // results of the successive writes, nil for success
+	writes int
+	delays []time.Duration
+	ok     bool
+}{
+	// Succeeds after transient errors, doubling the delay.
+	{
+		RetryPolicy{InitialDelay: time.Millisecond},
+		[]error{syscall.ENOBUFS, syscall.EAGAIN, syscall.ENOBUFS, nil},
+		4,
+		[]time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond},
+		true,
+	},
+	// The delay is clamped to MaxDelay.
+	{
+		RetryPolicy{InitialDelay: 3 * time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		[]error{syscall.ENOBUFS, syscall.ENOBUFS, syscall.ENOBUFS, syscall.ENOBUFS, nil},
+		5,
+		[]time.Duration{3 * time.Millisecond, 6 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		true,
+	},
+	// The default delays apply when zero.
+	{
+		RetryPolicy{},
+		[]error{syscall.ENOBUFS, syscall.ENOBUFS, nil},
+		3,
+		[]time.Duration{defaultRetryInitialDelay, 2 * defaultRetryInitialDelay},
+		true,
+	},
+	// MaxRetries caps the retries and the last transient error is
+	// returned.
+	{
+		RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond},
+		[]error{syscall.ENOBUFS, syscall.ENOBUFS, syscall.ENOBUFS, nil},
+		3,
+		[]time.Duration{time.Millisecond, 2 * time.Millisecond},
+		false,
+	},
+	// A permanent error is returned without retries.
+	{
+		RetryPolicy{InitialDelay: time.Millisecond},
+		[]error{syscall.EHOSTUNREACH, nil},
+		1,
+		nil,
+		false,
+	},
+}
+
+func TestWriteRetry(t *testing.T) {
+	for i, tt := range writeRetryTests {
+		var writes int
+		write := func() (int, error) {
+			err := tt.errs[writes]
+			writes++
+			if err != nil {
+				return 0, &net.OpError{Op: "write", Net: "udp4", Err: os.NewSyscallError("sendmsg", err)}
+			}
+			return 5, nil
+		}
+		var delays []time.Duration
+		n, err := writeRetry(write, tt.policy, func(d time.Duration) { delays = append(delays, d) })
+		if (err == nil) != tt.ok {
+			t.Errorf("#%d: got %v; expected success=%v", i, err, tt.ok)
+		}
+		if tt.ok && n != 5 {
+			t.Errorf("#%d: got %v; expected 5", i, n)
+		}
+		if writes != tt.writes {
+			t.Errorf("#%d: got %v writes; expected %v", i, writes, tt.writes)
+		}
+		if !reflect.DeepEqual(delays, tt.delays) {
+			t.Errorf("#%d: got delays %v; expected %v", i, delays, tt.delays)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+
+	"golang.org/x/net/internal/iana"
+)
+
+// References:
+//
+// RFC 1624  Computation of the Internet Checksum via Incremental Update
+//	http://tools.ietf.org/html/rfc1624
+
+// AdjustChecksum updates the header checksum field of the IPv4
+// header b in wire format after a 16-bit word of the header is
+// changed from oldWord to newWord, without recomputing the checksum
+// over the whole header.  It returns ErrHeaderTooShort and leaves b
+// unchanged when b is too short to hold the checksum field.
+func AdjustChecksum(b []byte, oldWord, newWord uint16) error {
+	if len(b) < posChecksum+2 {
+		return ErrHeaderTooShort
+	}
+	cs := uint16(b[posChecksum])<<8 | uint16(b[posChecksum+1])
+	cs = adjustChecksum(cs, oldWord, newWord)
+	b[posChecksum], b[posChecksum+1] = byte(cs>>8), byte(cs)
+	return nil
+}
+
+// adjustChecksum returns the checksum cs updated for the change of
+// a 16-bit word from oldWord to newWord, following equation 3 of
+// RFC 1624: HC' = ~(~HC + ~m + m').
+func adjustChecksum(cs, oldWord, newWord uint16) uint16 {
+	s := uint32(^cs) + uint32(^oldWord) + uint32(newWord)
+	for s>>16 != 0 {
+		s = s&0xffff + s>>16
+	}
+	return ^uint16(s)
+}
+
+// SetSrcInPlace overwrites the source address field of the IPv4
+// header b in wire format with ip and updates the header checksum
+// incrementally.
+func SetSrcInPlace(b []byte, ip net.IP) error {
+	return setAddrInPlace(b, posSrc, ip)
+}
+
+// SetDstInPlace overwrites the destination address field of the IPv4
+// header b in wire format with ip and updates the header checksum
+// incrementally.
+func SetDstInPlace(b []byte, ip net.IP) error {
+	return setAddrInPlace(b, posDst, ip)
+}
+
+func setAddrInPlace(b []byte, pos int, ip net.IP) error {
+	if len(b) < HeaderLen {
+		return ErrHeaderTooShort
+	}
+	ip = ip.To4()
+	if ip == nil {
+		return errNonIPv4Address
+	}
+	for i := 0; i < net.IPv4len; i += 2 {
+		oldWord := uint16(b[pos+i])<<8 | uint16(b[pos+i+1])
+		newWord := uint16(ip[i])<<8 | uint16(ip[i+1])
+		AdjustChecksum(b, oldWord, newWord)
+		b[pos+i], b[pos+i+1] = ip[i], ip[i+1]
+	}
+	return nil
+}
+
+// DecrementTTL decrements the TTL field of h by one, as a router
+// does on forwarding, and reports whether the datagram may be
+// forwarded.  It returns false and leaves h unchanged when the TTL
+// would reach zero, in which case the datagram should be dropped and
+// an ICMP time exceeded message sent back to the source.
+//
+// A non-zero Checksum field of h is updated incrementally, so that a
+// header parsed by ParseHeader keeps a valid checksum; a zero one is
+// left for the platform to fill in.
+func (h *Header) DecrementTTL() bool {
+	if h.TTL <= 1 {
+		return false
+	}
+	if h.Checksum != 0 {
+		oldWord := uint16(h.TTL)<<8 | uint16(h.Protocol)
+		newWord := uint16(h.TTL-1)<<8 | uint16(h.Protocol)
+		h.Checksum = int(adjustChecksum(uint16(h.Checksum), oldWord, newWord))
+	}
+	h.TTL--
+	return true
+}
+
+// DecrementTTLInPlace is like DecrementTTL but operates on the IPv4
+// header b in wire format, updating the header checksum
+// incrementally.  It returns false and leaves b unchanged when b is
+// shorter than HeaderLen or the TTL would reach zero.
+func DecrementTTLInPlace(b []byte) bool {
+	if len(b) < HeaderLen || b[posTTL] <= 1 {
+		return false
+	}
+	oldWord := uint16(b[posTTL])<<8 | uint16(b[posProtocol])
+	b[posTTL]--
+	AdjustChecksum(b, oldWord, oldWord-0x100)
+	return true
+}
+
+// UpdateTransportChecksum updates the transport checksum field at
+// offset off of the IPv4 packet b in wire format, such as 6 past the
+// header for UDP or 16 past the header for TCP, after a 16-bit word
+// covered by the checksum is changed from oldWord to newWord, without
+// recomputing the checksum over the whole segment.  Since the
+// checksum covers the pseudo header, a change of the source or
+// destination address in the IPv4 header needs to be reflected too.
+// It returns ErrHeaderTooShort when b is too short to hold the IPv4
+// header, and an error when off doesn't point past the header or b
+// is too short to hold the checksum field, leaving b unchanged.
+//
+// For UDP, a zero checksum field means no checksum was computed by
+// the sender and is left untouched, and an updated checksum of zero
+// is transmitted as all ones, as specified in RFC 768.
+func UpdateTransportChecksum(b []byte, off int, oldWord, newWord uint16) error {
+	if len(b) < HeaderLen {
+		return ErrHeaderTooShort
+	}
+	if off < HeaderLen || len(b) < off+2 {
+		return errBufferTooShort
+	}
+	cs := uint16(b[off])<<8 | uint16(b[off+1])
+	udp := b[posProtocol] == iana.ProtocolUDP
+	if udp && cs == 0 {
+		return nil
+	}
+	cs = adjustChecksum(cs, oldWord, newWord)
+	if udp && cs == 0 {
+		cs = 0xffff
+	}
+	b[off], b[off+1] = byte(cs>>8), byte(cs)
+	return nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"golang.org/x/net/internal/iana"
+)
+
+var wireHeader = []byte{
+	0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00,
+	0x40, 0x11, 0xb8, 0x61, 0xc0, 0xa8, 0x00, 0x01,
+	0xc0, 0xa8, 0x00, 0xc7,
+}
+
+// recomputedHeader returns a copy of b with the header checksum
+// computed over the whole header.
+func recomputedHeader(b []byte) []byte {
+	nb := make([]byte, len(b))
+	copy(nb, b)
+	nb[posChecksum], nb[posChecksum+1] = 0, 0
+	cs := checksum(nb)
+	nb[posChecksum], nb[posChecksum+1] = byte(cs>>8), byte(cs)
+	return nb
+}
+
+var setAddrInPlaceTests = []net.IP{
+	net.IPv4(10, 0, 0, 1),
+	net.IPv4(255, 255, 255, 255),
+	net.IPv4(0, 0, 0, 0),
+	net.IPv4(192, 168, 0, 1),
+	net.IPv4(172, 16, 255, 254).To4(),
+}
+
+func TestSetAddrInPlace(t *testing.T) {
+	for _, ip := range setAddrInPlaceTests {
+		b := make([]byte, len(wireHeader))
+		copy(b, wireHeader)
+		if err := SetSrcInPlace(b, ip); err != nil {
+			t.Fatalf("SetSrcInPlace failed: %v", err)
+		}
+		if err := SetDstInPlace(b, ip); err != nil {
+			t.Fatalf("SetDstInPlace failed: %v", err)
+		}
+		if !bytes.Equal(b[posSrc:posSrc+net.IPv4len], ip.To4()) || !bytes.Equal(b[posDst:posDst+net.IPv4len], ip.To4()) {
+			t.Fatalf("got %v; expected src and dst %v", b, ip)
+		}
+		if cs := checksum(b); cs != 0 {
+			t.Fatalf("got checksum verification result %#04x; expected 0", cs)
+		}
+		if nb := recomputedHeader(b); !bytes.Equal(b, nb) {
+			t.Fatalf("got %v; expected %v", b, nb)
+		}
+	}
+
+	b := make([]byte, len(wireHeader))
+	if err := SetDstInPlace(b[:HeaderLen-1], net.IPv4(10, 0, 0, 1)); err != ErrHeaderTooShort {
+		t.Fatalf("got %v; expected %v", err, ErrHeaderTooShort)
+	}
+	if err := SetDstInPlace(b, net.ParseIP("2001:db8::1")); err != errNonIPv4Address {
+		t.Fatalf("got %v; expected %v", err, errNonIPv4Address)
+	}
+}
+
+func TestAdjustChecksum(t *testing.T) {
+	for ttl := 0; ttl < 256; ttl++ {
+		b := make([]byte, len(wireHeader))
+		copy(b, wireHeader)
+		oldWord := uint16(b[posTTL])<<8 | uint16(b[posProtocol])
+		b[posTTL] = byte(ttl)
+		newWord := uint16(b[posTTL])<<8 | uint16(b[posProtocol])
+		if err := AdjustChecksum(b, oldWord, newWord); err != nil {
+			t.Fatalf("AdjustChecksum failed: %v", err)
+		}
+		if nb := recomputedHeader(b); !bytes.Equal(b, nb) {
+			t.Fatalf("ttl=%v: got %v; expected %v", ttl, b, nb)
+		}
+	}
+	if err := AdjustChecksum(make([]byte, posChecksum+1), 0, 1); err != ErrHeaderTooShort {
+		t.Fatalf("got %v; expected %v", err, ErrHeaderTooShort)
+	}
+}
+
+// transportPacket returns an IPv4 packet carrying a transport
+// segment of protocol proto with a checksum computed over the whole
+// segment and the pseudo header.  It also returns the offset of the
+// transport checksum field.
+func transportPacket(proto int, payload []byte) ([]byte, int) {
+	var seg []byte
+	var off int
+	switch proto {
+	case iana.ProtocolUDP:
+		seg = make([]byte, 8+len(payload))
+		seg[4], seg[5] = byte(len(seg)>>8), byte(len(seg))
+		off = 6
+	case iana.ProtocolTCP:
+		seg = make([]byte, 20+len(payload))
+		seg[4], seg[5], seg[6], seg[7] = 0xde, 0xad, 0xbe, 0xef // sequence number
+		seg[12] = 5 << 4                                        // data offset
+		seg[13] = 0x18                                          // PSH, ACK
+		seg[14], seg[15] = 0xff, 0xff                           // window
+		off = 16
+	}
+	seg[0], seg[1] = 0x30, 0x39 // source port
+	seg[2], seg[3] = 0x00, 0x35 // destination port
+	copy(seg[len(seg)-len(payload):], payload)
+	h := make([]byte, HeaderLen)
+	copy(h, wireHeader)
+	h[posProtocol] = byte(proto)
+	b := append(recomputedHeader(h), seg...)
+	off += HeaderLen
+	cs := transportChecksum(b)
+	b[off], b[off+1] = byte(cs>>8), byte(cs)
+	return b, off
+}
+
+// transportChecksum returns the transport checksum of the IPv4
+// packet b computed over the pseudo header and the whole segment.
+func transportChecksum(b []byte) uint16 {
+	seg := b[HeaderLen:]
+	ph := make([]byte, 12, 12+len(seg))
+	copy(ph, b[posSrc:posDst+net.IPv4len])
+	ph[9] = b[posProtocol]
+	ph[10], ph[11] = byte(len(seg)>>8), byte(len(seg))
+	return checksum(append(ph, seg...))
+}
+
+// recomputedPacket returns a copy of b with the transport checksum
+// field at off computed over the whole segment.
+func recomputedPacket(b []byte, off int) []byte {
+	nb := make([]byte, len(b))
+	copy(nb, b)
+	nb[off], nb[off+1] = 0, 0
+	cs := transportChecksum(nb)
+	if nb[posProtocol] == iana.ProtocolUDP && cs == 0 {
+		cs = 0xffff
+	}
+	nb[off], nb[off+1] = byte(cs>>8), byte(cs)
+	return nb
+}
+
+var updateTransportChecksumTests = []struct {
+	proto   int
+	payload []byte
+}{
+	{iana.ProtocolUDP, []byte("HELLO-R-U-THERE")},
+	{iana.ProtocolUDP, nil},
+	{iana.ProtocolTCP, []byte("HELLO-R-U-THERE")},
+	{iana.ProtocolTCP, nil},
+}
+
+func TestUpdateTransportChecksum(t *testing.T) {
+	for _, tt := range updateTransportChecksumTests {
+		for _, port := range []uint16{0, 1, 53, 8080, 0xffff} {
+			b, off := transportPacket(tt.proto, tt.payload)
+
+			// Rewrite the source port.
+			oldWord := uint16(b[HeaderLen])<<8 | uint16(b[HeaderLen+1])
+			b[HeaderLen], b[HeaderLen+1] = byte(port>>8), byte(port)
+			if err := UpdateTransportChecksum(b, off, oldWord, port); err != nil {
+				t.Fatalf("UpdateTransportChecksum failed: %v", err)
+			}
+			if nb := recomputedPacket(b, off); !bytes.Equal(b, nb) {
+				t.Fatalf("proto=%v, port=%v: got %v; expected %v", tt.proto, port, b, nb)
+			}
+
+			// Rewrite the source address, which is covered
+			// by both the header and the transport checksums.
+			ip := net.IPv4(10, 0, byte(port>>8), byte(port)).To4()
+			for i := 0; i < net.IPv4len; i += 2 {
+				oldWord := uint16(b[posSrc+i])<<8 | uint16(b[posSrc+i+1])
+				if err := UpdateTransportChecksum(b, off, oldWord, uint16(ip[i])<<8|uint16(ip[i+1])); err != nil {
+					t.Fatalf("UpdateTransportChecksum failed: %v", err)
+				}
+			}
+			if err := SetSrcInPlace(b, ip); err != nil {
+				t.Fatalf("SetSrcInPlace failed: %v", err)
+			}
+			if nb := recomputedPacket(b, off); !bytes.Equal(b, nb) {
+				t.Fatalf("proto=%v, port=%v: got %v; expected %v", tt.proto, port, b, nb)
+			}
+			if cs := checksum(b[:HeaderLen]); cs != 0 {
+				t.Fatalf("proto=%v, port=%v: got header checksum verification result %#04x; expected 0", tt.proto, port, cs)
+			}
+		}
+	}
+}
+
+func TestUpdateTransportChecksumUDPZero(t *testing.T) {
+	// A zero checksum field means no checksum was computed.
+	b, off := transportPacket(iana.ProtocolUDP, []byte("HELLO-R-U-THERE"))
+	b[off], b[off+1] = 0, 0
+	if err := UpdateTransportChecksum(b, off, 0x3039, 0x0001); err != nil {
+		t.Fatalf("UpdateTransportChecksum failed: %v", err)
+	}
+	if b[off] != 0 || b[off+1] != 0 {
+		t.Fatalf("got %#02x%02x; expected 0", b[off], b[off+1])
+	}
+
+	// An updated checksum of zero is transmitted as all ones.
+	b, off = transportPacket(iana.ProtocolUDP, []byte("HELLO-R-U-THERE"))
+	for port := 0; port <= 0xffff; port++ {
+		nb := make([]byte, len(b))
+		copy(nb, b)
+		nb[HeaderLen], nb[HeaderLen+1] = byte(port>>8), byte(port)
+		nb[off], nb[off+1] = 0, 0
+		if transportChecksum(nb) != 0 {
+			continue
+		}
+		if err := UpdateTransportChecksum(b, off, 0x3039, uint16(port)); err != nil {
+			t.Fatalf("UpdateTransportChecksum failed: %v", err)
+		}
+		if b[off] != 0xff || b[off+1] != 0xff {
+			t.Fatalf("got %#02x%02x; expected 0xffff", b[off], b[off+1])
+		}
+		return
+	}
+	t.Fatal("no port yields a zero checksum")
+}
+
+func TestUpdateTransportChecksumShortBuffer(t *testing.T) {
+	b, off := transportPacket(iana.ProtocolUDP, nil)
+	if err := UpdateTransportChecksum(b[:HeaderLen-1], off, 0, 1); err != ErrHeaderTooShort {
+		t.Fatalf("got %v; expected %v", err, ErrHeaderTooShort)
+	}
+	for _, off := range []int{-1, 0, posChecksum, len(b) - 1, len(b)} {
+		nb := make([]byte, len(b))
+		copy(nb, b)
+		if err := UpdateTransportChecksum(nb, off, 0, 1); err != errBufferTooShort {
+			t.Fatalf("off=%v: got %v; expected %v", off, err, errBufferTooShort)
+		}
+		if !bytes.Equal(nb, b) {
+			t.Fatalf("off=%v: got %v; expected %v", off, nb, b)
+		}
+	}
+}
+
+func TestDecrementTTL(t *testing.T) {
+	b := make([]byte, len(wireHeader))
+	copy(b, wireHeader)
+	h, err := ParseHeader(b)
+	if err != nil {
+		t.Fatalf("ParseHeader failed: %v", err)
+	}
+	for ttl := int(b[posTTL]); ttl > 1; ttl-- {
+		if !DecrementTTLInPlace(b) {
+			t.Fatalf("DecrementTTLInPlace failed at ttl %v", ttl)
+		}
+		if !h.DecrementTTL() {
+			t.Fatalf("Header.DecrementTTL failed at ttl %v", ttl)
+		}
+		if int(b[posTTL]) != ttl-1 || h.TTL != ttl-1 {
+			t.Fatalf("got %v and %v; expected %v", b[posTTL], h.TTL, ttl-1)
+		}
+		if cs := checksum(b); cs != 0 {
+			t.Fatalf("got checksum verification result %#04x; expected 0", cs)
+		}
+		if cs := int(b[posChecksum])<<8 | int(b[posChecksum+1]); h.Checksum != cs {
+			t.Fatalf("got %#04x; expected %#04x", h.Checksum, cs)
+		}
+	}
+	nb := make([]byte, len(b))
+	copy(nb, b)
+	if DecrementTTLInPlace(b) || !bytes.Equal(b, nb) {
+		t.Fatalf("DecrementTTLInPlace succeeded at ttl %v", b[posTTL])
+	}
+	ttl, cs := h.TTL, h.Checksum
+	if h.DecrementTTL() || h.TTL != ttl || h.Checksum != cs {
+		t.Fatalf("Header.DecrementTTL succeeded at ttl %v", h.TTL)
+	}
+}
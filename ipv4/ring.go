@@ -0,0 +1,128 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// A RingReader reads datagrams from an endpoint into a fixed number
+// of fixed-size slots allocated up front, which bounds the memory
+// used for holding the payloads of bursts of datagrams.  Only the
+// payloads are kept in the slots; each read still allocates the
+// source address, the buffer for the ancillary data and, when any
+// per packet option is received, the control message, as ReadFrom
+// does.
+type RingReader struct {
+	dropped uint64 // accessed atomically
+
+	c     *PacketConn
+	slots []ringSlot
+	free  chan int // slots available for reading
+	ready chan int // slots holding a datagram
+	err   error    // read error, valid once ready is closed
+}
+
+type ringSlot struct {
+	b    []byte
+	n    int
+	cm   *ControlMessage
+	addr net.Addr
+}
+
+// NewRingReader returns a new RingReader that reads datagrams from
+// the endpoint c into slots slots, each slotSize bytes long.  A
+// datagram longer than slotSize is truncated as it is by ReadFrom.
+// It returns nil when slots or slotSize is not positive.
+//
+// A goroutine started by NewRingReader keeps reading from c, and
+// stops when a read fails, such as after c is closed or the read
+// deadline expires.  No other reads should be made on c meanwhile.
+// When all the slots are in use, the goroutine still reads the
+// datagrams from c, to keep the socket receive buffer from
+// overflowing, and discards them unless a slot is released during
+// the read, counting them as dropped.  As the package provides no
+// batch read, the datagrams are read one at a time.
+func (c *PacketConn) NewRingReader(slots, slotSize int) *RingReader {
+	if !c.payloadHandler.ok() || slots <= 0 || slotSize <= 0 {
+		return nil
+	}
+	r := &RingReader{
+		c:     c,
+		slots: make([]ringSlot, slots),
+		free:  make(chan int, slots),
+		ready: make(chan int, slots),
+	}
+	b := make([]byte, slots*slotSize)
+	for i := range r.slots {
+		r.slots[i].b = b[i*slotSize : (i+1)*slotSize : (i+1)*slotSize]
+		r.free <- i
+	}
+	go r.run(make([]byte, slotSize))
+	return r
+}
+
+func (r *RingReader) run(scratch []byte) {
+	defer close(r.ready)
+	for {
+		select {
+		case i := <-r.free:
+			s := &r.slots[i]
+			n, cm, addr, err := r.c.ReadFrom(s.b)
+			if err != nil {
+				r.err = err
+				return
+			}
+			s.n, s.cm, s.addr = n, cm, addr
+			r.ready <- i
+		default:
+			n, cm, addr, err := r.c.ReadFrom(scratch)
+			if err != nil {
+				r.err = err
+				return
+			}
+			// A slot may have been released during the read.
+			select {
+			case i := <-r.free:
+				s := &r.slots[i]
+				s.n, s.cm, s.addr = copy(s.b, scratch[:n]), cm, addr
+				r.ready <- i
+			default:
+				atomic.AddUint64(&r.dropped, 1)
+			}
+		}
+	}
+}
+
+// Next waits for a datagram and returns its payload buf, its control
+// message cm and its source address addr, along with release, which
+// returns the slot holding the datagram to the ring.  It returns the
+// read error that stopped the reader once all the datagrams read
+// before the error are consumed.
+//
+// The buf refers to the slot and is valid only until release is
+// called.  The release function must be called for each datagram, as
+// soon as buf is no longer used; a slot not released is never reused,
+// and the reader drops the datagrams arriving while no slot is
+// available.  Calls to release after the first have no effect.  Next
+// is not safe for concurrent use, while release may be called from
+// any goroutine.
+func (r *RingReader) Next() (buf []byte, cm *ControlMessage, addr net.Addr, release func(), err error) {
+	i, ok := <-r.ready
+	if !ok {
+		return nil, nil, nil, nil, r.err
+	}
+	s := &r.slots[i]
+	var once sync.Once
+	return s.b[:s.n], s.cm, s.addr, func() { once.Do(func() { r.free <- i }) }, nil
+}
+
+// Dropped returns the number of datagrams discarded because no slot
+// was available.
+func (r *RingReader) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// servePool holds the read buffers used by Serve, shared by all the
+// endpoints.
+var servePool sync.Pool
+
+// Serve reads datagrams from the endpoint c in a loop and calls
+// handler for each of them, with the number of bytes read n, the
+// control message cm, the source address addr and the payload buf,
+// which holds the first n bytes read.  It returns when a read fails,
+// such as after c is closed or the read deadline expires, or when
+// handler returns a non-nil error, with the error.
+//
+// The read buffer, bufSize bytes long, is taken from a pool shared
+// by all the endpoints and put back when Serve returns, so the loop
+// allocates no buffer per datagram.  As the buffer is reused for the
+// next read, buf is valid only until handler returns; handler must
+// copy the payload to retain it.  A payload longer than bufSize is
+// truncated as it is by ReadFrom.
+func (c *PacketConn) Serve(bufSize int, handler func(n int, cm *ControlMessage, addr net.Addr, buf []byte) error) error {
+	if !c.payloadHandler.ok() || bufSize <= 0 || handler == nil {
+		return syscall.EINVAL
+	}
+	var b []byte
+	if pb, ok := servePool.Get().(*[]byte); ok && cap(*pb) >= bufSize {
+		b = (*pb)[:bufSize]
+	} else {
+		b = make([]byte, bufSize)
+	}
+	defer servePool.Put(&b)
+	for {
+		n, cm, addr, err := c.ReadFrom(b)
+		if err != nil {
+			return err
+		}
+		if err := handler(n, cm, addr, b[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadReply reads a datagram from the endpoint c into b, calls reply
+// with the payload req, the control message cm and the source address
+// from of the datagram, and writes the response resp returned by
+// reply to the address to, or to from when to is nil.  No response is
+// written when resp is nil.  An error returned by reply is returned
+// as is.
+//
+// The response is written with the control message returned by the
+// Reply method of cm, which makes the response leave the interface on
+// which the request arrived, with the destination address of the
+// request as the source address.  It keeps the replies of a server
+// on a multihomed host symmetric with the requests, as long as
+// FlagDst and FlagInterface are set by SetControlMessage; otherwise
+// the protocol stack selects the path of the response.
+//
+// The req refers to b and is valid only until reply returns.
+func (c *PacketConn) ReadReply(b []byte, reply func(req []byte, cm *ControlMessage, from net.Addr) (resp []byte, to net.Addr, err error)) error {
+	if !c.payloadHandler.ok() || reply == nil {
+		return syscall.EINVAL
+	}
+	n, cm, from, err := c.ReadFrom(b)
+	if err != nil {
+		return err
+	}
+	resp, to, err := reply(b[:n], cm, from)
+	if err != nil || resp == nil {
+		return err
+	}
+	if to == nil {
+		to = from
+	}
+	_, err = c.WriteTo(resp, cm.Reply(), to)
+	return err
+}
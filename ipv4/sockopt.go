@@ -15,9 +15,24 @@ const (
 	ssoReceiveDst                // header field on received packet
 	ssoReceiveInterface          // inbound interface on received packet
 	ssoPacketInfo                // incbound or outbound packet path
+	ssoReceiveFragSize           // largest fragment size on received packet
+	ssoReceiveTOS                // header field on received packet
+	ssoReceiveErr                // extended reliable error message passing
+	ssoIncomingCPU               // cpu on which received packet was processed
 	ssoHeaderPrepend             // ipv4 header
 	ssoJoinGroup                 // any-source multicast
 	ssoLeaveGroup                // any-source multicast
+	ssoPriority                  // protocol-defined priority for outgoing packets
+	ssoBroadcast                 // broadcast datagram transmission
+	ssoDontRoute                 // bypass of routing table lookup
+	ssoICMPFilter                // icmp filter
+	ssoTxTime                    // transmit time based packet scheduling
+	ssoUDPGRO                    // udp generic receive offload
+	ssoMemInfo                   // socket memory and drop counters
+	ssoPathMTU                   // path mtu of connected socket
+	ssoProtocol                  // protocol number of socket
+	ssoMark                      // mark for outgoing packets
+	ssoMTUDiscover               // path mtu discovery mode
 	ssoMax
 )
 
@@ -28,10 +43,14 @@ const (
 	ssoTypeInterface
 	ssoTypeIPMreq
 	ssoTypeIPMreqn
+	ssoTypeICMPFilter
+	ssoTypeSockTxtime
+	ssoTypeMemInfo
 )
 
 // A sockOpt represents a binding for sticky socket option.
 type sockOpt struct {
-	name int // option name, must be equal or greater than 1
-	typ  int // option value type, must be equal or greater than 1
+	level int // option level
+	name  int // option name, must be equal or greater than 1
+	typ   int // option value type, must be equal or greater than 1
 }
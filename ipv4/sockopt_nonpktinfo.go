@@ -7,9 +7,9 @@
 package ipv4
 
 func ipv4PacketInfo(fd int) (bool, error) {
-	return false, errOpNoSupport
+	return false, ErrNotSupported
 }
 
 func setIPv4PacketInfo(fd int, v bool) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
@@ -9,21 +9,21 @@ package ipv4
 import "net"
 
 func getInt(fd int, opt *sockOpt) (int, error) {
-	return 0, errOpNoSupport
+	return 0, ErrNotSupported
 }
 
 func setInt(fd int, opt *sockOpt, v int) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
 
 func getInterface(fd int, opt *sockOpt) (*net.Interface, error) {
-	return nil, errOpNoSupport
+	return nil, ErrNotSupported
 }
 
 func setInterface(fd int, opt *sockOpt, ifi *net.Interface) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
 
 func setGroup(fd int, opt *sockOpt, ifi *net.Interface, ip net.IP) error {
-	return errOpNoSupport
+	return ErrNotSupported
 }
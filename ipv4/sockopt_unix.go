@@ -9,14 +9,13 @@ package ipv4
 import (
 	"net"
 	"os"
+	"syscall"
 	"unsafe"
-
-	"golang.org/x/net/internal/iana"
 )
 
 func getInt(fd int, opt *sockOpt) (int, error) {
 	if opt.name < 1 || (opt.typ != ssoTypeByte && opt.typ != ssoTypeInt) {
-		return 0, errOpNoSupport
+		return 0, ErrNotSupported
 	}
 	var i int32
 	var b byte
@@ -26,7 +25,7 @@ func getInt(fd int, opt *sockOpt) (int, error) {
 		p = unsafe.Pointer(&b)
 		l = sysSockoptLen(1)
 	}
-	if err := getsockopt(fd, iana.ProtocolIP, opt.name, p, &l); err != nil {
+	if err := getsockopt(fd, opt.level, opt.name, p, &l); err != nil {
 		return 0, os.NewSyscallError("getsockopt", err)
 	}
 	if opt.typ == ssoTypeByte {
@@ -37,7 +36,7 @@ func getInt(fd int, opt *sockOpt) (int, error) {
 
 func setInt(fd int, opt *sockOpt, v int) error {
 	if opt.name < 1 || (opt.typ != ssoTypeByte && opt.typ != ssoTypeInt) {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	i := int32(v)
 	var b byte
@@ -48,12 +47,34 @@ func setInt(fd int, opt *sockOpt, v int) error {
 		p = unsafe.Pointer(&b)
 		l = sysSockoptLen(1)
 	}
-	return os.NewSyscallError("setsockopt", setsockopt(fd, iana.ProtocolIP, opt.name, p, l))
+	return os.NewSyscallError("setsockopt", setsockopt(fd, opt.level, opt.name, p, l))
+}
+
+func getICMPFilter(fd int, opt *sockOpt) (*ICMPFilter, error) {
+	if opt.name < 1 || opt.typ != ssoTypeICMPFilter {
+		return nil, ErrNotSupported
+	}
+	var f ICMPFilter
+	l := sysSockoptLen(sysSizeofICMPFilter)
+	if err := getsockopt(fd, opt.level, opt.name, unsafe.Pointer(&f.sysICMPFilter), &l); err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	return &f, nil
+}
+
+func setICMPFilter(fd int, opt *sockOpt, f *ICMPFilter) error {
+	if opt.name < 1 || opt.typ != ssoTypeICMPFilter {
+		return ErrNotSupported
+	}
+	if f == nil {
+		return syscall.EINVAL
+	}
+	return os.NewSyscallError("setsockopt", setsockopt(fd, opt.level, opt.name, unsafe.Pointer(&f.sysICMPFilter), sysSizeofICMPFilter))
 }
 
 func getInterface(fd int, opt *sockOpt) (*net.Interface, error) {
 	if opt.name < 1 {
-		return nil, errOpNoSupport
+		return nil, ErrNotSupported
 	}
 	switch opt.typ {
 	case ssoTypeInterface:
@@ -61,13 +82,13 @@ func getInterface(fd int, opt *sockOpt) (*net.Interface, error) {
 	case ssoTypeIPMreqn:
 		return getsockoptIPMreqn(fd, opt.name)
 	default:
-		return nil, errOpNoSupport
+		return nil, ErrNotSupported
 	}
 }
 
 func setInterface(fd int, opt *sockOpt, ifi *net.Interface) error {
 	if opt.name < 1 {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	switch opt.typ {
 	case ssoTypeInterface:
@@ -75,13 +96,22 @@ func setInterface(fd int, opt *sockOpt, ifi *net.Interface) error {
 	case ssoTypeIPMreqn:
 		return setsockoptIPMreqn(fd, opt.name, ifi, nil)
 	default:
-		return errOpNoSupport
+		return ErrNotSupported
+	}
+}
+
+func setInterfaceAddr(fd int, opt *sockOpt, ip net.IP) error {
+	if opt.name < 1 || opt.typ != ssoTypeInterface && opt.typ != ssoTypeIPMreqn {
+		return ErrNotSupported
 	}
+	var b [4]byte
+	copy(b[:], ip.To4())
+	return os.NewSyscallError("setsockopt", setsockopt(fd, opt.level, opt.name, unsafe.Pointer(&b[0]), sysSockoptLen(4)))
 }
 
 func setGroup(fd int, opt *sockOpt, ifi *net.Interface, grp net.IP) error {
 	if opt.name < 1 {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	switch opt.typ {
 	case ssoTypeIPMreq:
@@ -89,6 +119,6 @@ func setGroup(fd int, opt *sockOpt, ifi *net.Interface, grp net.IP) error {
 	case ssoTypeIPMreqn:
 		return setsockoptIPMreqn(fd, opt.name, ifi, grp)
 	default:
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 }
@@ -9,17 +9,15 @@ import (
 	"os"
 	"syscall"
 	"unsafe"
-
-	"golang.org/x/net/internal/iana"
 )
 
 func getInt(fd syscall.Handle, opt *sockOpt) (int, error) {
 	if opt.name < 1 || opt.typ != ssoTypeInt {
-		return 0, errOpNoSupport
+		return 0, ErrNotSupported
 	}
 	var i int32
 	l := int32(4)
-	if err := syscall.Getsockopt(fd, iana.ProtocolIP, int32(opt.name), (*byte)(unsafe.Pointer(&i)), &l); err != nil {
+	if err := syscall.Getsockopt(fd, int32(opt.level), int32(opt.name), (*byte)(unsafe.Pointer(&i)), &l); err != nil {
 		return 0, os.NewSyscallError("getsockopt", err)
 	}
 	return int(i), nil
@@ -27,29 +25,46 @@ func getInt(fd syscall.Handle, opt *sockOpt) (int, error) {
 
 func setInt(fd syscall.Handle, opt *sockOpt, v int) error {
 	if opt.name < 1 || opt.typ != ssoTypeInt {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	i := int32(v)
-	return os.NewSyscallError("setsockopt", syscall.Setsockopt(fd, iana.ProtocolIP, int32(opt.name), (*byte)(unsafe.Pointer(&i)), 4))
+	return os.NewSyscallError("setsockopt", syscall.Setsockopt(fd, int32(opt.level), int32(opt.name), (*byte)(unsafe.Pointer(&i)), 4))
+}
+
+func getICMPFilter(fd syscall.Handle, opt *sockOpt) (*ICMPFilter, error) {
+	return nil, ErrNotSupported
+}
+
+func setICMPFilter(fd syscall.Handle, opt *sockOpt, f *ICMPFilter) error {
+	return ErrNotSupported
 }
 
 func getInterface(fd syscall.Handle, opt *sockOpt) (*net.Interface, error) {
 	if opt.name < 1 || opt.typ != ssoTypeInterface {
-		return nil, errOpNoSupport
+		return nil, ErrNotSupported
 	}
 	return getsockoptInterface(fd, opt.name)
 }
 
 func setInterface(fd syscall.Handle, opt *sockOpt, ifi *net.Interface) error {
 	if opt.name < 1 || opt.typ != ssoTypeInterface {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	return setsockoptInterface(fd, opt.name, ifi)
 }
 
+func setInterfaceAddr(fd syscall.Handle, opt *sockOpt, ip net.IP) error {
+	if opt.name < 1 || opt.typ != ssoTypeInterface {
+		return ErrNotSupported
+	}
+	var b [4]byte
+	copy(b[:], ip.To4())
+	return os.NewSyscallError("setsockopt", syscall.Setsockopt(fd, int32(opt.level), int32(opt.name), (*byte)(unsafe.Pointer(&b[0])), 4))
+}
+
 func setGroup(fd syscall.Handle, opt *sockOpt, ifi *net.Interface, grp net.IP) error {
 	if opt.name < 1 || opt.typ != ssoTypeIPMreq {
-		return errOpNoSupport
+		return ErrNotSupported
 	}
 	return setsockoptIPMreq(fd, opt.name, ifi, grp)
 }
@@ -0,0 +1,168 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+)
+
+// References:
+//
+// RFC 6724  Default Address Selection for Internet Protocol Version 6 (IPv6)
+//	http://tools.ietf.org/html/rfc6724
+
+var errNoSourceAddress = errors.New("no suitable source address")
+
+// A srcCandidate represents a candidate source address assigned to
+// an interface.
+type srcCandidate struct {
+	ipn *net.IPNet
+	ifi *net.Interface
+}
+
+// SelectSourceAddr returns a local IPv4 address suitable as the
+// source address for the destination dst, along with the interface
+// to which the address is assigned.  It is useful on a multihomed
+// host to pick a source on the same scope as the destination, such
+// as a private address for a destination in the private address
+// space.
+//
+// The selection applies the rules of RFC 6724 that make sense for
+// IPv4 to the addresses assigned to the interfaces that are up, in
+// the following order:
+//
+//  1. prefer the address equal to dst
+//  2. prefer the address of the appropriate scope
+//  3. prefer the address on the subnet covering dst
+//  4. prefer the address sharing the longest prefix with dst
+//
+// The scopes are, from the narrowest: loopback, link-local, private
+// including the shared address space and administratively scoped
+// multicast, and global.  The rule on deprecated addresses is not
+// applied since the deprecation state of IPv4 addresses is not
+// available through the net package.  Loopback addresses are
+// considered only for loopback destinations.  Ties are broken by
+// the order in which the interfaces and addresses are enumerated.
+//
+// Unlike the protocol stack, SelectSourceAddr doesn't consult the
+// routing table, so the address may not be the one the protocol
+// stack would pick for an unconnected socket.
+func SelectSourceAddr(dst net.IP) (net.IP, *net.Interface, error) {
+	if dst = dst.To4(); dst == nil {
+		return nil, nil, errNonIPv4Address
+	}
+	ift, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	var cands []srcCandidate
+	for i := range ift {
+		if ift[i].Flags&net.FlagUp == 0 {
+			continue
+		}
+		ifat, err := ift[i].Addrs()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ifa := range ifat {
+			if ipn, ok := ifa.(*net.IPNet); ok && ipn.IP.To4() != nil {
+				cands = append(cands, srcCandidate{ipn: ipn, ifi: &ift[i]})
+			}
+		}
+	}
+	c, ok := selectSourceAddr(dst, cands)
+	if !ok {
+		return nil, nil, errNoSourceAddress
+	}
+	return c.ipn.IP.To4(), c.ifi, nil
+}
+
+// selectSourceAddr returns the candidate in cands most suitable as
+// the source address for the IPv4 address dst.  It reports false
+// when no candidate is suitable.
+func selectSourceAddr(dst net.IP, cands []srcCandidate) (srcCandidate, bool) {
+	var best srcCandidate
+	found := false
+	for _, c := range cands {
+		ac := Classify(c.ipn.IP)
+		if ac&(AddrUnspecified|AddrMulticast|AddrBroadcast|AddrReserved) != 0 || ac == AddrLoopback && addrScope(dst) != scopeLoopback {
+			continue
+		}
+		if !found || preferSource(dst, c, best) {
+			best, found = c, true
+		}
+	}
+	return best, found
+}
+
+// preferSource reports whether the candidate a is preferred over the
+// candidate b as the source address for dst.
+func preferSource(dst net.IP, a, b srcCandidate) bool {
+	// Rule 1: prefer same address.
+	if sa, sb := a.ipn.IP.Equal(dst), b.ipn.IP.Equal(dst); sa != sb {
+		return sa
+	}
+	// Rule 2: prefer appropriate scope.
+	if sa, sb, sd := addrScope(a.ipn.IP), addrScope(b.ipn.IP), addrScope(dst); sa != sb {
+		if sa < sb {
+			return sa >= sd
+		}
+		return sb < sd
+	}
+	// Rule 5 adapted: prefer the subnet covering the destination.
+	if sa, sb := a.ipn.Contains(dst), b.ipn.Contains(dst); sa != sb {
+		return sa
+	}
+	// Rule 8: use longest matching prefix.
+	return commonPrefixLen(a.ipn.IP, dst) > commonPrefixLen(b.ipn.IP, dst)
+}
+
+const (
+	scopeLoopback = iota
+	scopeLinkLocal
+	scopePrivate
+	scopeGlobal
+)
+
+// addrScope returns the scope of the IPv4 address ip.
+func addrScope(ip net.IP) int {
+	switch Classify(ip) {
+	case AddrLoopback:
+		return scopeLoopback
+	case AddrLinkLocal, AddrBroadcast:
+		return scopeLinkLocal
+	case AddrPrivate, AddrCGNAT:
+		return scopePrivate
+	case AddrMulticast:
+		switch {
+		case IsLinkLocalMulticast(ip):
+			return scopeLinkLocal
+		case IsAdminScopedMulticast(ip):
+			return scopePrivate
+		}
+	}
+	return scopeGlobal
+}
+
+// commonPrefixLen returns the length of the longest prefix shared by
+// the IPv4 addresses a and b in bits.
+func commonPrefixLen(a, b net.IP) int {
+	a, b = a.To4(), b.To4()
+	n := 0
+	for i := 0; i < net.IPv4len; i++ {
+		x := a[i] ^ b[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return n
+}
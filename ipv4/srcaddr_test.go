@@ -0,0 +1,66 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"testing"
+)
+
+func mustCIDR(s string) *net.IPNet {
+	ip, ipn, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	ipn.IP = ip.To4()
+	return ipn
+}
+
+var selectSourceAddrTests = []struct {
+	dst   net.IP
+	cands []string
+	src   net.IP // nil when no candidate is suitable
+}{
+	// Same address.
+	{net.IPv4(192, 168, 0, 1), []string{"10.0.0.1/8", "192.168.0.1/24"}, net.IPv4(192, 168, 0, 1)},
+
+	// Appropriate scope.
+	{net.IPv4(10, 1, 2, 3), []string{"198.51.100.1/24", "192.168.0.1/24"}, net.IPv4(192, 168, 0, 1)},
+	{net.IPv4(169, 254, 1, 1), []string{"198.51.100.1/24", "169.254.9.9/16"}, net.IPv4(169, 254, 9, 9)},
+	{net.IPv4(8, 8, 8, 8), []string{"169.254.9.9/16", "192.168.0.1/24", "198.51.100.1/24"}, net.IPv4(198, 51, 100, 1)},
+	{net.IPv4(8, 8, 8, 8), []string{"169.254.9.9/16", "192.168.0.1/24"}, net.IPv4(192, 168, 0, 1)},
+	{net.IPv4(224, 0, 0, 251), []string{"198.51.100.1/24", "169.254.9.9/16"}, net.IPv4(169, 254, 9, 9)},
+
+	// Same subnet.
+	{net.IPv4(192, 168, 1, 10), []string{"10.0.0.1/8", "192.168.1.20/24"}, net.IPv4(192, 168, 1, 20)},
+	{net.IPv4(10, 1, 2, 3), []string{"10.0.0.1/30", "192.168.0.1/16", "10.1.0.1/16"}, net.IPv4(10, 1, 0, 1)},
+
+	// Longest matching prefix.
+	{net.IPv4(172, 16, 5, 5), []string{"10.0.0.1/24", "172.17.0.1/24", "192.168.0.1/24"}, net.IPv4(172, 17, 0, 1)},
+
+	// Loopback.
+	{net.IPv4(127, 0, 0, 1), []string{"192.168.0.1/24", "127.0.0.1/8"}, net.IPv4(127, 0, 0, 1)},
+	{net.IPv4(8, 8, 8, 8), []string{"127.0.0.1/8"}, nil},
+	{net.IPv4(8, 8, 8, 8), nil, nil},
+}
+
+func TestSelectSourceAddr(t *testing.T) {
+	for i, tt := range selectSourceAddrTests {
+		var cands []srcCandidate
+		for j, s := range tt.cands {
+			cands = append(cands, srcCandidate{ipn: mustCIDR(s), ifi: &net.Interface{Index: j + 1}})
+		}
+		c, ok := selectSourceAddr(tt.dst.To4(), cands)
+		if !ok {
+			if tt.src != nil {
+				t.Errorf("#%d: got none; expected %v", i, tt.src)
+			}
+			continue
+		}
+		if !c.ipn.IP.Equal(tt.src) {
+			t.Errorf("#%d: got %v; expected %v", i, c.ipn.IP, tt.src)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// maxSourceFilterLen is the maximum number of the source addresses
+// for SetSourceFilter, which is bounded by the 8-bit jump offset of
+// the BPF conditional jump instruction.
+const maxSourceFilterLen = 255
+
+// SetSourceFilter installs a socket filter on the endpoint that
+// makes the protocol stack discard the received packets by source
+// address.  When allow is true, only the packets from sources are
+// accepted; otherwise the packets from sources are discarded and
+// the others are accepted.  It replaces the socket filter previously
+// installed on the endpoint.  The sources must consist of at most
+// 255 IPv4 addresses.
+//
+// Note that the packets queued before the call are not filtered.
+// Currently only Linux supports this.
+func (c *PacketConn) SetSourceFilter(sources []net.IP, allow bool) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	prog, err := sourceFilter(sources, allow)
+	if err != nil {
+		return err
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return err
+	}
+	return os.NewSyscallError("setsockopt", syscall.AttachLsf(fd, prog))
+}
+
+// sourceFilter returns a BPF program that loads the source address
+// field of the IPv4 header, compares it with each of sources, and
+// accepts or discards the packet according to allow.  The negative
+// offset from the network header makes the program work regardless
+// of the transport protocol of the endpoint.
+func sourceFilter(sources []net.IP, allow bool) ([]syscall.SockFilter, error) {
+	if len(sources) > maxSourceFilterLen {
+		return nil, syscall.EINVAL
+	}
+	const (
+		drop   = 0
+		accept = 0xffffffff
+	)
+	match, nomatch := uint32(accept), uint32(drop)
+	if !allow {
+		match, nomatch = nomatch, match
+	}
+	n := len(sources)
+	prog := make([]syscall.SockFilter, 0, n+3)
+	prog = append(prog, *syscall.LsfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, sysSKF_NET_OFF+12))
+	for i, src := range sources {
+		ip := src.To4()
+		if ip == nil {
+			return nil, errNonIPv4Address
+		}
+		k := int(ip[0])<<24 | int(ip[1])<<16 | int(ip[2])<<8 | int(ip[3])
+		prog = append(prog, *syscall.LsfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, k, n-i, 0))
+	}
+	prog = append(prog, syscall.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: nomatch})
+	prog = append(prog, syscall.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: match})
+	return prog, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+// runSourceFilter interprets the subset of BPF used by sourceFilter
+// against a packet from src.
+func runSourceFilter(t *testing.T, prog []syscall.SockFilter, src net.IP) uint32 {
+	var a uint32
+	for pc := 0; pc < len(prog); pc++ {
+		ins := prog[pc]
+		switch ins.Code {
+		case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS:
+			if int32(ins.K) != sysSKF_NET_OFF+12 {
+				t.Fatalf("got load offset %d; expected %d", int32(ins.K), sysSKF_NET_OFF+12)
+			}
+			ip := src.To4()
+			a = uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+		case syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K:
+			if a == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case syscall.BPF_RET | syscall.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("unexpected instruction %#v", ins)
+		}
+	}
+	t.Fatal("program fell off the end")
+	return 0
+}
+
+func TestSourceFilter(t *testing.T) {
+	sources := []net.IP{net.IPv4(192, 0, 2, 1), net.IPv4(192, 0, 2, 2), net.IPv4(198, 51, 100, 1)}
+	for _, allow := range []bool{true, false} {
+		prog, err := sourceFilter(sources, allow)
+		if err != nil {
+			t.Fatalf("sourceFilter failed: %v", err)
+		}
+		for _, tt := range []struct {
+			src    net.IP
+			listed bool
+		}{
+			{net.IPv4(192, 0, 2, 1), true},
+			{net.IPv4(192, 0, 2, 2), true},
+			{net.IPv4(198, 51, 100, 1), true},
+			{net.IPv4(192, 0, 2, 3), false},
+			{net.IPv4(203, 0, 113, 1), false},
+		} {
+			accepted := runSourceFilter(t, prog, tt.src) != 0
+			if accepted != (tt.listed == allow) {
+				t.Errorf("allow=%v, src=%v: got %v; expected %v", allow, tt.src, accepted, tt.listed == allow)
+			}
+		}
+	}
+
+	if _, err := sourceFilter([]net.IP{net.ParseIP("2001:db8::1")}, true); err != errNonIPv4Address {
+		t.Fatalf("got %v; expected %v", err, errNonIPv4Address)
+	}
+	if _, err := sourceFilter(make([]net.IP, maxSourceFilterLen+1), true); err != syscall.EINVAL {
+		t.Fatalf("got %v; expected %v", err, syscall.EINVAL)
+	}
+	many := make([]net.IP, maxSourceFilterLen)
+	for i := range many {
+		many[i] = net.IPv4(10, 0, byte(i>>8), byte(i))
+	}
+	prog, err := sourceFilter(many, true)
+	if err != nil {
+		t.Fatalf("sourceFilter failed: %v", err)
+	}
+	if runSourceFilter(t, prog, many[len(many)-1]) == 0 || runSourceFilter(t, prog, net.IPv4(10, 1, 0, 0)) != 0 {
+		t.Fatal("got wrong verdict with the maximum number of sources")
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+import "net"
+
+// SetSourceFilter installs a socket filter on the endpoint that
+// makes the protocol stack discard the received packets by source
+// address.
+// Currently only Linux supports this.
+func (c *PacketConn) SetSourceFilter(sources []net.IP, allow bool) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// BPFStats returns the number of packets the protocol stack
+// discarded for the endpoint, either because the attached socket
+// filter rejected them or because the receive buffer was full; the
+// two causes are counted together.  Unlike the PACKET_STATISTICS
+// option of packet sockets, the protocol stack keeps no count of
+// packets received by IP-level sockets, hence no such counter is
+// provided.
+// Currently only Linux 4.12 or above supports this.
+func (c *PacketConn) BPFStats() (dropped uint64, err error) {
+	if !c.payloadHandler.ok() {
+		return 0, syscall.EINVAL
+	}
+	fd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return 0, err
+	}
+	mi, err := getMemInfo(fd, &sockOpts[ssoMemInfo])
+	if err != nil {
+		return 0, err
+	}
+	return uint64(mi[sysSK_MEMINFO_DROPS]), nil
+}
+
+func getMemInfo(fd int, opt *sockOpt) ([sysSK_MEMINFO_VARS]uint32, error) {
+	var mi [sysSK_MEMINFO_VARS]uint32
+	if opt.name < 1 || opt.typ != ssoTypeMemInfo {
+		return mi, ErrNotSupported
+	}
+	l := sysSockoptLen(4 * len(mi))
+	if err := getsockopt(fd, opt.level, opt.name, unsafe.Pointer(&mi[0]), &l); err != nil {
+		if err == syscall.ENOPROTOOPT {
+			return mi, ErrNotSupported
+		}
+		return mi, os.NewSyscallError("getsockopt", err)
+	}
+	if l < 4*(sysSK_MEMINFO_DROPS+1) {
+		return mi, ErrNotSupported
+	}
+	return mi, nil
+}
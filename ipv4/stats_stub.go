@@ -0,0 +1,14 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+// BPFStats returns the number of packets the protocol stack
+// discarded for the endpoint.
+// Currently only Linux supports this.
+func (c *PacketConn) BPFStats() (dropped uint64, err error) {
+	return 0, ErrNotSupported
+}
@@ -0,0 +1,17 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build nacl plan9 solaris windows
+
+package ipv4
+
+import "net"
+
+// Swap replaces the underlying transport of the endpoint with
+// newConn and returns the previous one.
+// Currently only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and
+// OpenBSD support this.
+func (c *PacketConn) Swap(newConn net.PacketConn) (old net.PacketConn, err error) {
+	return nil, ErrNotSupported
+}
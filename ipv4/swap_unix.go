@@ -0,0 +1,104 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ipv4
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// Swap replaces the underlying transport of the endpoint with
+// newConn and returns the previous one, which is left open for the
+// caller to close.  It is useful to rebind the endpoint after an
+// address change without recreating the state built on top of it.
+//
+// Before the replacement, Swap carries the TTL, TOS and multicast
+// TTL values, the per packet IP-level socket options turned on, the
+// generic receive offload setting and the groups joined through
+// JoinGroup over to newConn.  On failure the endpoint is left
+// unchanged, and newConn may have some of the options applied.
+//
+// The replacement is atomic: each method of the endpoint uses either
+// the previous transport or newConn throughout the call.  After the
+// replacement, the read deadline of the previous transport is set to
+// the past, so that reads in progress on it fail with a timeout error
+// and the callers can retry on the endpoint, which then reads from
+// newConn.  The previous transport keeps its own options and
+// memberships until it is closed.  Group memberships are changed
+// atomically with the replacement, whereas the other options set
+// while Swap is in progress may be applied to the previous transport
+// only.
+func (c *PacketConn) Swap(newConn net.PacketConn) (old net.PacketConn, err error) {
+	if !c.payloadHandler.ok() || newConn == nil {
+		return nil, syscall.EINVAL
+	}
+	t, ok := c.payloadHandler.PacketConn.(*transport)
+	if !ok {
+		return nil, ErrInvalidConnType
+	}
+	nc, ok := newConn.(net.Conn)
+	if !ok {
+		return nil, ErrInvalidConnType
+	}
+	nfd, err := sysfd(nc)
+	if err != nil {
+		return nil, err
+	}
+	// Holding the lock on the memberships serializes Swap with
+	// itself and with JoinGroup and LeaveGroup.
+	c.dgramOpt.groups.Lock()
+	defer c.dgramOpt.groups.Unlock()
+	ofd, err := c.payloadHandler.sysfd()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range []int{ssoTTL, ssoTOS, ssoMulticastTTL} {
+		v, err := getInt(ofd, &sockOpts[i])
+		if err == ErrNotSupported {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := setInt(nfd, &sockOpts[i], v); err != nil {
+			return nil, err
+		}
+	}
+	c.payloadHandler.rawOpt.RLock()
+	cf := c.payloadHandler.rawOpt.cflags
+	gro := c.payloadHandler.rawOpt.gro
+	c.payloadHandler.rawOpt.RUnlock()
+	_, isIP := newConn.(*net.IPConn)
+	if !isIP {
+		cf &^= FlagRawHeader
+	}
+	if cf != 0 {
+		if err := setControlMessage(nfd, &c.payloadHandler.rawOpt, cf, true); err != nil {
+			return nil, err
+		}
+	}
+	if gro {
+		if err := setInt(nfd, &sockOpts[ssoUDPGRO], 1); err != nil {
+			return nil, err
+		}
+	}
+	for m, ifi := range c.dgramOpt.groups.m {
+		if err := setGroup(nfd, &sockOpts[ssoJoinGroup], ifi, net.IP(m.group[:])); err != nil {
+			return nil, err
+		}
+	}
+	old = t.load()
+	t.store(newConn)
+	if !isIP {
+		c.payloadHandler.rawOpt.Lock()
+		c.payloadHandler.rawOpt.clear(FlagRawHeader)
+		c.payloadHandler.rawOpt.Unlock()
+	}
+	old.SetReadDeadline(time.Unix(1, 0))
+	return old, nil
+}
@@ -4,25 +4,48 @@
 
 package ipv4
 
+import (
+	"syscall"
+
+	"golang.org/x/net/internal/iana"
+)
+
 type sysSockoptLen int32
 
 var (
 	ctlOpts = [ctlMax]ctlOpt{
 		ctlTTL:        {sysIP_TTL, 1, marshalTTL, parseTTL},
 		ctlPacketInfo: {sysIP_PKTINFO, sysSizeofInetPktinfo, marshalPacketInfo, parsePacketInfo},
+		ctlFragSize:   {sysIP_RECVFRAGSIZE, 4, marshalFragSize, parseFragSize},
+		ctlTOS:        {sysIP_TOS, 1, marshalTOS, parseTOS},
 	}
 
 	sockOpts = [ssoMax]sockOpt{
-		ssoTOS:                {sysIP_TOS, ssoTypeInt},
-		ssoTTL:                {sysIP_TTL, ssoTypeInt},
-		ssoMulticastTTL:       {sysIP_MULTICAST_TTL, ssoTypeInt},
-		ssoMulticastInterface: {sysIP_MULTICAST_IF, ssoTypeIPMreqn},
-		ssoMulticastLoopback:  {sysIP_MULTICAST_LOOP, ssoTypeInt},
-		ssoReceiveTTL:         {sysIP_RECVTTL, ssoTypeInt},
-		ssoPacketInfo:         {sysIP_PKTINFO, ssoTypeInt},
-		ssoHeaderPrepend:      {sysIP_HDRINCL, ssoTypeInt},
-		ssoJoinGroup:          {sysIP_ADD_MEMBERSHIP, ssoTypeIPMreqn},
-		ssoLeaveGroup:         {sysIP_DROP_MEMBERSHIP, ssoTypeIPMreqn},
+		ssoTOS:                {iana.ProtocolIP, sysIP_TOS, ssoTypeInt},
+		ssoTTL:                {iana.ProtocolIP, sysIP_TTL, ssoTypeInt},
+		ssoMulticastTTL:       {iana.ProtocolIP, sysIP_MULTICAST_TTL, ssoTypeInt},
+		ssoMulticastInterface: {iana.ProtocolIP, sysIP_MULTICAST_IF, ssoTypeIPMreqn},
+		ssoMulticastLoopback:  {iana.ProtocolIP, sysIP_MULTICAST_LOOP, ssoTypeInt},
+		ssoReceiveTTL:         {iana.ProtocolIP, sysIP_RECVTTL, ssoTypeInt},
+		ssoPacketInfo:         {iana.ProtocolIP, sysIP_PKTINFO, ssoTypeInt},
+		ssoReceiveFragSize:    {iana.ProtocolIP, sysIP_RECVFRAGSIZE, ssoTypeInt},
+		ssoReceiveTOS:         {iana.ProtocolIP, sysIP_RECVTOS, ssoTypeInt},
+		ssoReceiveErr:         {iana.ProtocolIP, sysIP_RECVERR, ssoTypeInt},
+		ssoIncomingCPU:        {syscall.SOL_SOCKET, sysSO_INCOMING_CPU, ssoTypeInt},
+		ssoHeaderPrepend:      {iana.ProtocolIP, sysIP_HDRINCL, ssoTypeInt},
+		ssoJoinGroup:          {iana.ProtocolIP, sysIP_ADD_MEMBERSHIP, ssoTypeIPMreqn},
+		ssoLeaveGroup:         {iana.ProtocolIP, sysIP_DROP_MEMBERSHIP, ssoTypeIPMreqn},
+		ssoPriority:           {syscall.SOL_SOCKET, syscall.SO_PRIORITY, ssoTypeInt},
+		ssoICMPFilter:         {syscall.SOL_RAW, sysICMP_FILTER, ssoTypeICMPFilter},
+		ssoBroadcast:          {syscall.SOL_SOCKET, syscall.SO_BROADCAST, ssoTypeInt},
+		ssoDontRoute:          {syscall.SOL_SOCKET, syscall.SO_DONTROUTE, ssoTypeInt},
+		ssoTxTime:             {syscall.SOL_SOCKET, sysSO_TXTIME, ssoTypeSockTxtime},
+		ssoUDPGRO:             {iana.ProtocolUDP, sysUDP_GRO, ssoTypeInt},
+		ssoMemInfo:            {syscall.SOL_SOCKET, sysSO_MEMINFO, ssoTypeMemInfo},
+		ssoPathMTU:            {iana.ProtocolIP, sysIP_MTU, ssoTypeInt},
+		ssoProtocol:           {syscall.SOL_SOCKET, syscall.SO_PROTOCOL, ssoTypeInt},
+		ssoMark:               {syscall.SOL_SOCKET, syscall.SO_MARK, ssoTypeInt},
+		ssoMTUDiscover:        {iana.ProtocolIP, sysIP_MTU_DISCOVER, ssoTypeInt},
 	}
 )
 
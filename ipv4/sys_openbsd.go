@@ -7,6 +7,8 @@ package ipv4
 import (
 	"net"
 	"syscall"
+
+	"golang.org/x/net/internal/iana"
 )
 
 type sysSockoptLen int32
@@ -19,16 +21,18 @@ var (
 	}
 
 	sockOpts = [ssoMax]sockOpt{
-		ssoTOS:                {sysIP_TOS, ssoTypeInt},
-		ssoTTL:                {sysIP_TTL, ssoTypeInt},
-		ssoMulticastTTL:       {sysIP_MULTICAST_TTL, ssoTypeByte},
-		ssoMulticastInterface: {sysIP_MULTICAST_IF, ssoTypeInterface},
-		ssoMulticastLoopback:  {sysIP_MULTICAST_LOOP, ssoTypeByte},
-		ssoReceiveTTL:         {sysIP_RECVTTL, ssoTypeInt},
-		ssoReceiveDst:         {sysIP_RECVDSTADDR, ssoTypeInt},
-		ssoReceiveInterface:   {sysIP_RECVIF, ssoTypeInt},
-		ssoHeaderPrepend:      {sysIP_HDRINCL, ssoTypeInt},
-		ssoJoinGroup:          {sysIP_ADD_MEMBERSHIP, ssoTypeIPMreq},
-		ssoLeaveGroup:         {sysIP_DROP_MEMBERSHIP, ssoTypeIPMreq},
+		ssoTOS:                {iana.ProtocolIP, sysIP_TOS, ssoTypeInt},
+		ssoTTL:                {iana.ProtocolIP, sysIP_TTL, ssoTypeInt},
+		ssoMulticastTTL:       {iana.ProtocolIP, sysIP_MULTICAST_TTL, ssoTypeByte},
+		ssoMulticastInterface: {iana.ProtocolIP, sysIP_MULTICAST_IF, ssoTypeInterface},
+		ssoMulticastLoopback:  {iana.ProtocolIP, sysIP_MULTICAST_LOOP, ssoTypeByte},
+		ssoReceiveTTL:         {iana.ProtocolIP, sysIP_RECVTTL, ssoTypeInt},
+		ssoReceiveDst:         {iana.ProtocolIP, sysIP_RECVDSTADDR, ssoTypeInt},
+		ssoReceiveInterface:   {iana.ProtocolIP, sysIP_RECVIF, ssoTypeInt},
+		ssoHeaderPrepend:      {iana.ProtocolIP, sysIP_HDRINCL, ssoTypeInt},
+		ssoJoinGroup:          {iana.ProtocolIP, sysIP_ADD_MEMBERSHIP, ssoTypeIPMreq},
+		ssoLeaveGroup:         {iana.ProtocolIP, sysIP_DROP_MEMBERSHIP, ssoTypeIPMreq},
+		ssoBroadcast:          {syscall.SOL_SOCKET, syscall.SO_BROADCAST, ssoTypeInt},
+		ssoDontRoute:          {syscall.SOL_SOCKET, syscall.SO_DONTROUTE, ssoTypeInt},
 	}
 )
@@ -0,0 +1,71 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// A transport holds the underlying transport of a PacketConn and
+// forwards the methods of net.Conn and net.PacketConn to it.  The
+// connection is kept in an atomic.Value, which allows Swap to replace
+// it while other goroutines use the endpoint.
+type transport struct {
+	v atomic.Value // transportConn
+}
+
+// A transportConn wraps the connection held by a transport, as the
+// values stored in an atomic.Value must be of the same type.
+type transportConn struct {
+	c net.PacketConn
+}
+
+func newTransport(c net.PacketConn) *transport {
+	t := &transport{}
+	t.store(c)
+	return t
+}
+
+func (t *transport) load() net.PacketConn   { return t.v.Load().(transportConn).c }
+func (t *transport) store(c net.PacketConn) { t.v.Store(transportConn{c: c}) }
+
+func (t *transport) Read(b []byte) (int, error)  { return t.load().(net.Conn).Read(b) }
+func (t *transport) Write(b []byte) (int, error) { return t.load().(net.Conn).Write(b) }
+
+func (t *transport) ReadFrom(b []byte) (int, net.Addr, error) { return t.load().ReadFrom(b) }
+
+func (t *transport) WriteTo(b []byte, addr net.Addr) (int, error) { return t.load().WriteTo(b, addr) }
+
+func (t *transport) Close() error                       { return t.load().Close() }
+func (t *transport) LocalAddr() net.Addr                { return t.load().LocalAddr() }
+func (t *transport) RemoteAddr() net.Addr               { return t.load().(net.Conn).RemoteAddr() }
+func (t *transport) SetDeadline(d time.Time) error      { return t.load().SetDeadline(d) }
+func (t *transport) SetReadDeadline(d time.Time) error  { return t.load().SetReadDeadline(d) }
+func (t *transport) SetWriteDeadline(d time.Time) error { return t.load().SetWriteDeadline(d) }
+
+// transportConnOf returns the connection currently held by c when c
+// is a *transport, or c itself otherwise.
+func transportConnOf(c net.PacketConn) net.PacketConn {
+	if t, ok := c.(*transport); ok {
+		return t.load()
+	}
+	return c
+}
+
+// conn returns the underlying transport of the endpoint.
+func (c *genericOpt) conn() net.Conn {
+	if t, ok := c.Conn.(*transport); ok {
+		return t.load().(net.Conn)
+	}
+	return c.Conn
+}
+
+// conn returns the underlying transport of the endpoint.
+func (c *dgramOpt) conn() net.PacketConn { return transportConnOf(c.PacketConn) }
+
+// conn returns the underlying transport of the endpoint.
+func (c *payloadHandler) conn() net.PacketConn { return transportConnOf(c.PacketConn) }
@@ -0,0 +1,147 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	errInvalidOption  = errors.New("invalid option")
+	errOptionNotFound = errors.New("option not found")
+)
+
+const optInternetTimestamp = 68 // internet timestamp option type
+
+// A TimestampFlag represents the flag field of the Internet
+// Timestamp option.
+type TimestampFlag int
+
+const (
+	TimestampOnly         TimestampFlag = 0 // timestamps only
+	TimestampAndAddress   TimestampFlag = 1 // each timestamp preceded by the address of the registering host
+	TimestampPrespecified TimestampFlag = 3 // timestamps registered by the prespecified hosts only
+)
+
+// A TimestampEntry represents an entry of the Internet Timestamp
+// option.
+type TimestampEntry struct {
+	Addr      net.IP // address of registering host, nil for TimestampOnly
+	Timestamp uint32 // milliseconds since midnight UT
+}
+
+// An InternetTimestampOption represents the Internet Timestamp option
+// described in RFC 791.
+type InternetTimestampOption struct {
+	Flag     TimestampFlag    // flag
+	Overflow int              // number of hosts unable to register timestamps due to lack of space
+	Entries  []TimestampEntry // entries including the unused ones
+	Used     int              // number of entries already registered
+}
+
+func (o *InternetTimestampOption) entryLen() int {
+	if o.Flag == TimestampOnly {
+		return 4
+	}
+	return 8
+}
+
+// Marshal returns the binary encoding of the Internet Timestamp
+// option o, which may be appended to the Options field of Header.
+// To request timestamps from the hosts along the path, the Entries
+// field must hold as many unused entries as the timestamps wanted,
+// each of which carries the address of a prespecified host when the
+// flag is TimestampPrespecified.
+func (o *InternetTimestampOption) Marshal() ([]byte, error) {
+	if o == nil {
+		return nil, errInvalidOption
+	}
+	switch o.Flag {
+	case TimestampOnly, TimestampAndAddress, TimestampPrespecified:
+	default:
+		return nil, errInvalidOption
+	}
+	l := 4 + o.entryLen()*len(o.Entries)
+	if len(o.Entries) == 0 || l > maxHeaderLen-HeaderLen || o.Used < 0 || o.Used > len(o.Entries) || o.Overflow < 0 || o.Overflow > 0x0f {
+		return nil, errInvalidOption
+	}
+	b := make([]byte, l)
+	b[0], b[1] = optInternetTimestamp, byte(l)
+	b[2] = byte(5 + o.entryLen()*o.Used)
+	b[3] = byte(o.Overflow<<4 | int(o.Flag))
+	p := b[4:]
+	for _, e := range o.Entries {
+		if o.Flag != TimestampOnly {
+			if ip := e.Addr.To4(); ip != nil {
+				copy(p[:4], ip)
+			} else if e.Addr != nil {
+				return nil, errNonIPv4Address
+			}
+			p = p[4:]
+		}
+		p[0], p[1], p[2], p[3] = byte(e.Timestamp>>24), byte(e.Timestamp>>16), byte(e.Timestamp>>8), byte(e.Timestamp)
+		p = p[4:]
+	}
+	return b, nil
+}
+
+// ParseInternetTimestampOption looks for the Internet Timestamp
+// option in b, the options of an IPv4 header such as the Options
+// field of Header, and parses it.  It validates the pointer and the
+// overflow fields of the option; the pointer must point to the
+// beginning of an entry and the overflow must be zero unless all the
+// entries are used.
+func ParseInternetTimestampOption(b []byte) (*InternetTimestampOption, error) {
+	for len(b) > 0 {
+		if b[0] == 0 { // end of option list
+			break
+		}
+		if b[0] == 1 { // no operation
+			b = b[1:]
+			continue
+		}
+		if len(b) < 2 || int(b[1]) < 2 || int(b[1]) > len(b) {
+			return nil, errInvalidOption
+		}
+		if b[0] == optInternetTimestamp {
+			return parseInternetTimestampOption(b[:b[1]])
+		}
+		b = b[b[1]:]
+	}
+	return nil, errOptionNotFound
+}
+
+func parseInternetTimestampOption(b []byte) (*InternetTimestampOption, error) {
+	if len(b) < 4 {
+		return nil, errInvalidOption
+	}
+	o := &InternetTimestampOption{Flag: TimestampFlag(b[3] & 0x0f), Overflow: int(b[3] >> 4)}
+	switch o.Flag {
+	case TimestampOnly, TimestampAndAddress, TimestampPrespecified:
+	default:
+		return nil, errInvalidOption
+	}
+	el := o.entryLen()
+	ptr := int(b[2])
+	if (len(b)-4)%el != 0 || ptr < 5 || (ptr-5)%el != 0 || ptr > len(b)+1 {
+		return nil, errInvalidOption
+	}
+	o.Used = (ptr - 5) / el
+	if o.Overflow != 0 && ptr <= len(b) {
+		return nil, errInvalidOption
+	}
+	for p := b[4:]; len(p) > 0; p = p[el:] {
+		var e TimestampEntry
+		q := p
+		if o.Flag != TimestampOnly {
+			e.Addr = net.IPv4(q[0], q[1], q[2], q[3])
+			q = q[4:]
+		}
+		e.Timestamp = uint32(q[0])<<24 | uint32(q[1])<<16 | uint32(q[2])<<8 | uint32(q[3])
+		o.Entries = append(o.Entries, e)
+	}
+	return o, nil
+}
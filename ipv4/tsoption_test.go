@@ -0,0 +1,83 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4_test
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+var internetTimestampOptionTests = []struct {
+	wire []byte
+	opt  ipv4.InternetTimestampOption
+}{
+	{
+		[]byte{68, 12, 9, 0x00, 0x01, 0x02, 0x03, 0x04, 0x00, 0x00, 0x00, 0x00},
+		ipv4.InternetTimestampOption{
+			Flag:    ipv4.TimestampOnly,
+			Entries: []ipv4.TimestampEntry{{Timestamp: 0x01020304}, {}},
+			Used:    1,
+		},
+	},
+	{
+		[]byte{68, 20, 21, 0x21, 192, 0, 2, 1, 0, 0, 0, 1, 198, 51, 100, 1, 0, 0, 0, 2},
+		ipv4.InternetTimestampOption{
+			Flag:     ipv4.TimestampAndAddress,
+			Overflow: 2,
+			Entries:  []ipv4.TimestampEntry{{net.IPv4(192, 0, 2, 1), 1}, {net.IPv4(198, 51, 100, 1), 2}},
+			Used:     2,
+		},
+	},
+	{
+		[]byte{68, 12, 5, 0x03, 192, 0, 2, 1, 0, 0, 0, 0},
+		ipv4.InternetTimestampOption{
+			Flag:    ipv4.TimestampPrespecified,
+			Entries: []ipv4.TimestampEntry{{Addr: net.IPv4(192, 0, 2, 1)}},
+		},
+	},
+}
+
+func TestInternetTimestampOption(t *testing.T) {
+	for i, tt := range internetTimestampOptionTests {
+		b, err := tt.opt.Marshal()
+		if err != nil {
+			t.Fatalf("#%v: ipv4.InternetTimestampOption.Marshal failed: %v", i, err)
+		}
+		if !bytes.Equal(b, tt.wire) {
+			t.Fatalf("#%v: got %#v; expected %#v", i, b, tt.wire)
+		}
+		opts := append([]byte{1}, tt.wire...) // preceded by no operation
+		o, err := ipv4.ParseInternetTimestampOption(opts)
+		if err != nil {
+			t.Fatalf("#%v: ipv4.ParseInternetTimestampOption failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(o, &tt.opt) {
+			t.Fatalf("#%v: got %#v; expected %#v", i, o, &tt.opt)
+		}
+	}
+}
+
+var invalidInternetTimestampOptionTests = [][]byte{
+	{68, 3, 5},                                // too short
+	{68, 10, 5, 0x00, 0, 0, 0, 0, 0, 0},       // length not on entry boundary
+	{68, 8, 4, 0x00, 0, 0, 0, 0},              // pointer too small
+	{68, 8, 6, 0x00, 0, 0, 0, 0},              // pointer not on entry boundary
+	{68, 8, 13, 0x00, 0, 0, 0, 0},             // pointer beyond option
+	{68, 12, 5, 0x10, 0, 0, 0, 0, 0, 0, 0, 0}, // overflow with unused entries
+	{68, 12, 5, 0x02, 0, 0, 0, 0, 0, 0, 0, 0}, // unknown flag
+	{7, 3, 4}, // no timestamp option
+}
+
+func TestParseInvalidInternetTimestampOption(t *testing.T) {
+	for i, b := range invalidInternetTimestampOptionTests {
+		if _, err := ipv4.ParseInternetTimestampOption(b); err == nil {
+			t.Errorf("#%v: ipv4.ParseInternetTimestampOption succeeded for %#v", i, b)
+		}
+	}
+}
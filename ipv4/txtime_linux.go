@@ -0,0 +1,65 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// SetTxTime sets whether WriteTo passes the TxTime field of the
+// control message to the protocol stack, which holds the outgoing
+// packet until the specified time.  The clockid specifies the clock
+// against which the time is measured, such as CLOCK_TAI (11) or
+// CLOCK_MONOTONIC (1).  The time is passed as the number of
+// nanoseconds returned by TxTime.UnixNano, so the application must
+// construct TxTime accordingly when clockid isn't CLOCK_REALTIME.
+// Packets are paced only when the outgoing interface runs a queuing
+// discipline that honors the transmit time, such as etf or fq.
+//
+// Turning the option off doesn't clear the SO_TXTIME socket option,
+// since the kernel doesn't allow it; WriteTo just stops passing the
+// transmit time and packets are sent immediately.
+// Currently only Linux supports this.
+func (c *PacketConn) SetTxTime(clockid int, on bool) error {
+	if !c.payloadHandler.ok() {
+		return syscall.EINVAL
+	}
+	if on {
+		fd, err := c.payloadHandler.sysfd()
+		if err != nil {
+			return err
+		}
+		if err := setSockTxtime(fd, &sockOpts[ssoTxTime], clockid); err != nil {
+			return err
+		}
+	}
+	c.payloadHandler.rawOpt.Lock()
+	c.payloadHandler.rawOpt.txtime = on
+	c.payloadHandler.rawOpt.Unlock()
+	return nil
+}
+
+func setSockTxtime(fd int, opt *sockOpt, clockid int) error {
+	if opt.name < 1 || opt.typ != ssoTypeSockTxtime {
+		return ErrNotSupported
+	}
+	st := sysSockTxtime{Clockid: int32(clockid)}
+	return os.NewSyscallError("setsockopt", setsockopt(fd, opt.level, opt.name, unsafe.Pointer(&st), sysSizeofSockTxtime))
+}
+
+// appendTxTime appends the SCM_TXTIME control message carrying t to
+// oob.
+func appendTxTime(oob []byte, t time.Time) []byte {
+	b := make([]byte, syscall.CmsgSpace(8))
+	m := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	m.Level = syscall.SOL_SOCKET
+	m.Type = sysSCM_TXTIME
+	m.SetLen(syscall.CmsgLen(8))
+	*(*uint64)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = uint64(t.UnixNano())
+	return append(oob, b...)
+}
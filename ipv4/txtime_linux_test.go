@@ -0,0 +1,34 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestAppendTxTime(t *testing.T) {
+	tm := time.Unix(1234567890, 123456789)
+	oob := appendTxTime(nil, tm)
+	cmsgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		t.Fatalf("syscall.ParseSocketControlMessage failed: %v", err)
+	}
+	if len(cmsgs) != 1 {
+		t.Fatalf("got %v control messages; expected 1", len(cmsgs))
+	}
+	m := cmsgs[0]
+	if m.Header.Level != syscall.SOL_SOCKET || m.Header.Type != sysSCM_TXTIME {
+		t.Fatalf("got level=%v, type=%v; expected level=%v, type=%v", m.Header.Level, m.Header.Type, syscall.SOL_SOCKET, sysSCM_TXTIME)
+	}
+	if len(m.Data) < 8 {
+		t.Fatalf("got %v bytes of data; expected 8", len(m.Data))
+	}
+	if v := *(*uint64)(unsafe.Pointer(&m.Data[0])); v != uint64(tm.UnixNano()) {
+		t.Fatalf("got %v; expected %v", v, tm.UnixNano())
+	}
+}
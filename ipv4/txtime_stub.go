@@ -0,0 +1,21 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package ipv4
+
+import "time"
+
+// SetTxTime sets whether WriteTo passes the TxTime field of the
+// control message to the protocol stack, which holds the outgoing
+// packet until the specified time.
+// Currently only Linux supports this.
+func (c *PacketConn) SetTxTime(clockid int, on bool) error {
+	return ErrNotSupported
+}
+
+func appendTxTime(oob []byte, t time.Time) []byte {
+	return oob
+}
@@ -5,12 +5,16 @@
 package ipv4_test
 
 import (
+	"errors"
 	"net"
 	"os"
+	"reflect"
 	"runtime"
+	"syscall"
 	"testing"
 	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/net/internal/iana"
 	"golang.org/x/net/internal/icmp"
 	"golang.org/x/net/internal/nettest"
@@ -67,6 +71,100 @@ func TestPacketConnReadWriteUnicastUDP(t *testing.T) {
 	}
 }
 
+func TestPacketConnReadControlMessageUnicastUDP(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if err := p.SetControlMessage(ipv4.FlagTTL|ipv4.FlagDst|ipv4.FlagInterface, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+	if err := p.SetTTL(7); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetTTL failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	_, cm, _, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if cm == nil {
+		t.Fatal("got no control message")
+	}
+	if cm.TTL != 7 {
+		t.Errorf("got ttl %v; expected 7", cm.TTL)
+	}
+	if !cm.Dst.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("got dst %v; expected 127.0.0.1", cm.Dst)
+	}
+	if cm.IfIndex != ifi.Index {
+		t.Errorf("got ifindex %v; expected %v", cm.IfIndex, ifi.Index)
+	}
+}
+
+func TestPacketConnWriteToOutgoingPath(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux":
+	default:
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	ifi := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback)
+	if ifi == nil {
+		t.Skipf("not available on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if err := p.SetControlMessage(ipv4.FlagDst|ipv4.FlagInterface, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+
+	// FreeBSD doesn't support specifying the outgoing interface.
+	wcm := &ipv4.ControlMessage{Src: net.IPv4(127, 0, 0, 1)}
+	if runtime.GOOS != "freebsd" {
+		wcm.IfIndex = ifi.Index
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), wcm, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	_, rcm, src, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if ip := src.(*net.UDPAddr).IP; !ip.Equal(wcm.Src) {
+		t.Fatalf("got source %v; expected %v", ip, wcm.Src)
+	}
+	if rcm == nil || rcm.IfIndex != ifi.Index {
+		t.Fatalf("got %v; expected interface index %v", rcm, ifi.Index)
+	}
+}
+
 func TestPacketConnReadWriteUnicastICMP(t *testing.T) {
 	switch runtime.GOOS {
 	case "nacl", "plan9", "solaris", "windows":
@@ -227,3 +325,1081 @@ func TestRawConnReadWriteUnicastICMP(t *testing.T) {
 		}
 	}
 }
+
+func TestRawConnWriteToN(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	r, err := ipv4.NewRawConn(c)
+	if err != nil {
+		t.Fatalf("ipv4.NewRawConn failed: %v", err)
+	}
+	defer r.Close()
+
+	wb, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{
+			ID: os.Getpid() & 0xffff, Seq: 1,
+			Data: []byte("HELLO-R-U-THERE"),
+		},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatalf("icmp.Message.Marshal failed: %v", err)
+	}
+	wh := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(wb),
+		TTL:      1,
+		Protocol: 1,
+		Dst:      net.IPv4(127, 0, 0, 1),
+	}
+	n, err := r.WriteToN(wh, wb, nil)
+	if err != nil {
+		t.Fatalf("ipv4.RawConn.WriteToN failed: %v", err)
+	}
+	if n != ipv4.HeaderLen+len(wb) {
+		t.Fatalf("got %v; expected %v", n, ipv4.HeaderLen+len(wb))
+	}
+	if _, err := r.WriteToN(wh, wb, &ipv4.ControlMessage{Dst: net.ParseIP("2001:db8::1")}); err == nil {
+		t.Fatal("ipv4.RawConn.WriteToN succeeded with non-IPv4 destination")
+	}
+}
+
+func TestPacketConnTryReadFrom(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	rb := make([]byte, 128)
+	if _, _, _, ok, err := p.TryReadFrom(rb); err != nil {
+		t.Fatalf("ipv4.PacketConn.TryReadFrom failed: %v", err)
+	} else if ok {
+		t.Fatal("ipv4.PacketConn.TryReadFrom returned a datagram on an idle endpoint")
+	}
+
+	wb := []byte("HELLO-R-U-THERE")
+	if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		n, _, src, ok, err := p.TryReadFrom(rb)
+		if err != nil {
+			t.Fatalf("ipv4.PacketConn.TryReadFrom failed: %v", err)
+		}
+		if !ok {
+			continue
+		}
+		if string(rb[:n]) != string(wb) || src.String() != c.LocalAddr().String() {
+			t.Fatalf("got %q from %v; expected %q from %v", rb[:n], src, wb, c.LocalAddr())
+		}
+		return
+	}
+	t.Fatal("ipv4.PacketConn.TryReadFrom didn't return the datagram")
+}
+
+func TestPacketConnWaitReadable(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.WaitReadable(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; expected %v", err, context.DeadlineExceeded)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	if err := p.WaitReadable(context.Background()); err == nil {
+		t.Fatal("ipv4.PacketConn.WaitReadable succeeded")
+	} else if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+	if err := p.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+
+	wb := []byte("HELLO-R-U-THERE")
+	if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.WaitReadable(ctx); err != nil {
+		t.Fatalf("ipv4.PacketConn.WaitReadable failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	n, _, _, ok, err := p.TryReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.TryReadFrom failed: %v", err)
+	}
+	if !ok || string(rb[:n]) != string(wb) {
+		t.Fatalf("got %q; expected %q", rb[:n], wb)
+	}
+
+	// A successful wait leaves the read deadline alone even when ctx
+	// is done right after it.
+	if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	if err := p.WaitReadable(ctx); err != nil {
+		t.Fatalf("ipv4.PacketConn.WaitReadable failed: %v", err)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, _, _, err := p.ReadFrom(rb); err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+}
+
+func TestPacketConnPeekFrom(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if err := p.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	if _, _, err := p.PeekFrom(make([]byte, 5)); err == nil {
+		t.Fatal("ipv4.PacketConn.PeekFrom succeeded")
+	} else if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+	if err := p.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+
+	wb := []byte("HELLO-R-U-THERE")
+	if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	pb := make([]byte, 5)
+	n, src, err := p.PeekFrom(pb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.PeekFrom failed: %v", err)
+	}
+	if string(pb[:n]) != string(wb[:5]) || src.String() != c.LocalAddr().String() {
+		t.Fatalf("got %q from %v; expected %q from %v", pb[:n], src, wb[:5], c.LocalAddr())
+	}
+	p.SetReadDeadline(time.Now().Add(time.Second))
+	rb := make([]byte, 128)
+	if n, _, _, err = p.ReadFrom(rb); err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if string(rb[:n]) != string(wb) {
+		t.Fatalf("got %q; expected %q", rb[:n], wb)
+	}
+}
+
+func TestPacketConnSetDeadlineContext(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop, err := p.SetDeadlineContext(ctx)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetDeadlineContext failed: %v", err)
+	}
+	defer stop()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, err := p.ReadFrom(make([]byte, 128))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+			t.Fatalf("got %v; expected timeout error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ipv4.PacketConn.ReadFrom isn't canceled")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stop, err = p.SetDeadlineContext(ctx)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetDeadlineContext failed: %v", err)
+	}
+	if _, _, _, err := p.ReadFrom(make([]byte, 128)); err == nil {
+		t.Fatal("ipv4.PacketConn.ReadFrom succeeded after deadline")
+	}
+	stop()
+
+	// A stopped watcher leaves the deadlines alone when ctx is done.
+	ctx, cancel = context.WithCancel(context.Background())
+	stop, err = p.SetDeadlineContext(ctx)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.SetDeadlineContext failed: %v", err)
+	}
+	stop()
+	stop()
+	if err := p.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetDeadline failed: %v", err)
+	}
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if _, _, _, err := p.ReadFrom(make([]byte, 128)); err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+}
+
+func TestPacketConnBPFStats(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	dropped, err := p.BPFStats()
+	if runtime.GOOS != "linux" {
+		if err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if err == ipv4.ErrNotSupported {
+		t.Skipf("ipv4.PacketConn.BPFStats failed: %v", err) // SO_MEMINFO appeared in Linux 4.12
+	}
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.BPFStats failed: %v", err)
+	}
+	if dropped != 0 {
+		t.Fatalf("got %v; expected 0", dropped)
+	}
+}
+
+func TestPacketConnSetCloseLinger(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if err := p.SetCloseLinger(time.Minute); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetCloseLinger failed: %v", err)
+	}
+	if err := p.SetCloseLinger(-1); err == nil {
+		t.Fatal("ipv4.PacketConn.SetCloseLinger succeeded with negative duration")
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+			t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+		}
+	}
+	start := time.Now()
+	if err := p.Close(); err != nil {
+		t.Fatalf("ipv4.PacketConn.Close failed: %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("ipv4.PacketConn.Close took %v; expected to return immediately", d)
+	}
+}
+
+func TestPacketConnSetSourceFilter(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	src := []net.IP{net.IPv4(127, 0, 0, 1)}
+	if err := p.SetSourceFilter(src, false); err != nil {
+		if runtime.GOOS != "linux" && err == ipv4.ErrNotSupported {
+			return
+		}
+		t.Fatalf("ipv4.PacketConn.SetSourceFilter failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	p.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, _, err := p.ReadFrom(rb); err == nil {
+		t.Fatal("got a packet from the denied source")
+	} else if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+
+	if err := p.SetSourceFilter(src, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetSourceFilter failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	p.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, _, err := p.ReadFrom(rb); err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+}
+
+func TestRawConnProtocol(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	for _, tt := range []struct {
+		network string
+		proto   int
+	}{
+		{"ip4:icmp", iana.ProtocolICMP},
+		{"ip4:udp", iana.ProtocolUDP},
+	} {
+		c, err := net.ListenPacket(tt.network, "0.0.0.0")
+		if err != nil {
+			t.Fatalf("net.ListenPacket failed: %v", err)
+		}
+		defer c.Close()
+		r, err := ipv4.NewRawConn(c)
+		if err != nil {
+			t.Fatalf("ipv4.NewRawConn failed: %v", err)
+		}
+		proto := r.Protocol()
+		if runtime.GOOS != "linux" {
+			if proto != -1 {
+				t.Fatalf("got %v; expected -1", proto)
+			}
+			continue
+		}
+		if proto != tt.proto {
+			t.Fatalf("%s: got %v; expected %v", tt.network, proto, tt.proto)
+		}
+	}
+}
+
+func TestIPPacketConnReadWrite(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	// Protocol number 253 is reserved for experimentation and
+	// testing by RFC 3692.
+	c, err := net.ListenIP("ip4:253", &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("net.ListenIP failed: %v", err)
+	}
+	defer c.Close()
+	p, err := ipv4.NewIPPacketConn(c)
+	if err != nil {
+		t.Fatalf("ipv4.NewIPPacketConn failed: %v", err)
+	}
+	cf := p.SupportedControlFlags() & (ipv4.FlagDst | ipv4.FlagRawHeader)
+	if err := p.SetControlMessage(cf, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+
+	wb := []byte("HELLO-R-U-THERE")
+	if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	n, cm, _, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if string(rb[:n]) != string(wb) {
+		t.Fatalf("got %q; expected %q", rb[:n], wb)
+	}
+	if cf&ipv4.FlagRawHeader != 0 && (cm == nil || cm.Header == nil || cm.Header.Protocol != 253) {
+		t.Fatalf("got %v; expected header of protocol 253", cm)
+	}
+
+	// An endpoint expecting the header from the application is
+	// rejected.
+	rc, err := net.ListenIP("ip4:253", &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("net.ListenIP failed: %v", err)
+	}
+	defer rc.Close()
+	if _, err := ipv4.NewRawConn(rc); err != nil {
+		t.Fatalf("ipv4.NewRawConn failed: %v", err)
+	}
+	if _, err := ipv4.NewIPPacketConn(rc); err != ipv4.ErrInvalidConnType {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrInvalidConnType)
+	}
+}
+
+func TestRawConnConnectWrite(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	r, err := ipv4.NewRawConn(c)
+	if err != nil {
+		t.Fatalf("ipv4.NewRawConn failed: %v", err)
+	}
+	defer r.Close()
+
+	wb, err := ipv4.NewEchoRequest(os.Getpid()&0xffff, 1, []byte("HELLO-R-U-THERE"))
+	if err != nil {
+		t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+	}
+	wh := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(wb),
+		TTL:      1,
+		Protocol: iana.ProtocolICMP,
+	}
+	if err := r.Write(wh, wb); err != ipv4.ErrMissingAddress {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrMissingAddress)
+	}
+	if err := r.Connect(net.IPv4(127, 0, 0, 1)); err != nil {
+		t.Fatalf("ipv4.RawConn.Connect failed: %v", err)
+	}
+	if err := r.Write(wh, wb); err != nil {
+		t.Fatalf("ipv4.RawConn.Write failed: %v", err)
+	}
+	if wh.Dst != nil {
+		t.Fatalf("ipv4.RawConn.Write modified header: %v", wh)
+	}
+	rb := make([]byte, ipv4.HeaderLen+128)
+	for {
+		if err := r.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("ipv4.RawConn.SetReadDeadline failed: %v", err)
+		}
+		h, b, _, err := r.ReadFrom(rb)
+		if err != nil {
+			t.Fatalf("ipv4.RawConn.ReadFrom failed: %v", err)
+		}
+		if !h.Src.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("got datagram from %v; expected from connected peer", h.Src)
+		}
+		if _, _, _, err := ipv4.ParseEchoReply(b); err == nil {
+			return
+		}
+	}
+}
+
+func TestRawReadsIncludeHeader(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		if ipv4.RawReadsIncludeHeader() {
+			t.Fatalf("got true; expected false on %q", runtime.GOOS)
+		}
+		return
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	wb, err := ipv4.NewEchoRequest(os.Getpid()&0xffff, 1, []byte("HELLO-R-U-THERE"))
+	if err != nil {
+		t.Fatalf("ipv4.NewEchoRequest failed: %v", err)
+	}
+	if _, err := c.WriteTo(wb, &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}); err != nil {
+		t.Fatalf("net.PacketConn.WriteTo failed: %v", err)
+	}
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	rb := make([]byte, 128)
+	n, _, _, _, err := c.(*net.IPConn).ReadMsgIP(rb, nil)
+	if err != nil {
+		t.Fatalf("net.IPConn.ReadMsgIP failed: %v", err)
+	}
+	// An ICMP message never begins with 0x4?, the first octet of
+	// a header, since no ICMP message type of 64 or above is
+	// sent in response to an echo request.
+	if included := n >= ipv4.HeaderLen && rb[0]>>4 == ipv4.Version; included != ipv4.RawReadsIncludeHeader() {
+		t.Fatalf("got %v; expected %v", ipv4.RawReadsIncludeHeader(), included)
+	}
+}
+
+func TestPacketConnSetTxTime(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	const clockMonotonic = 1
+	if runtime.GOOS != "linux" {
+		if err := p.SetTxTime(clockMonotonic, true); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if err := p.SetTxTime(clockMonotonic, true); err != nil {
+		t.Skipf("ipv4.PacketConn.SetTxTime failed: %v", err) // SO_TXTIME appeared in Linux 4.19
+	}
+	// A zero transmit time is never passed to the protocol stack.
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), &ipv4.ControlMessage{}, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetTxTime(clockMonotonic, false); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetTxTime failed: %v", err)
+	}
+}
+
+func TestRawConnReadFromFiltered(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	r, err := ipv4.NewRawConn(c)
+	if err != nil {
+		t.Fatalf("ipv4.NewRawConn failed: %v", err)
+	}
+	defer r.Close()
+
+	id := os.Getpid() & 0xffff
+	wb, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{
+			ID: id, Seq: 1,
+			Data: []byte("HELLO-R-U-THERE"),
+		},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatalf("icmp.Message.Marshal failed: %v", err)
+	}
+	wh := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(wb),
+		TTL:      1,
+		Protocol: 1,
+		Dst:      net.IPv4(127, 0, 0, 1),
+	}
+	if err := r.WriteTo(wh, wb, nil); err != nil {
+		t.Fatalf("ipv4.RawConn.WriteTo failed: %v", err)
+	}
+	if err := r.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.RawConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, ipv4.HeaderLen+128)
+	accept := func(h *ipv4.Header, p []byte) bool {
+		m, err := icmp.ParseMessage(iana.ProtocolICMP, p)
+		if err != nil || m.Type != ipv4.ICMPTypeEchoReply {
+			return false
+		}
+		echo, ok := m.Body.(*icmp.Echo)
+		return ok && echo.ID == id
+	}
+	_, p, _, err := r.ReadFromFiltered(rb, accept)
+	if err != nil {
+		t.Fatalf("ipv4.RawConn.ReadFromFiltered failed: %v", err)
+	}
+	if !accept(nil, p) {
+		t.Fatalf("got unexpected packet %v", p)
+	}
+}
+
+func TestPacketConnReadFromFiltered(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	for _, s := range []string{"SKIP", "SKIP", "HELLO-R-U-THERE"} {
+		if _, err := p.WriteTo([]byte(s), nil, c.LocalAddr()); err != nil {
+			t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+		}
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	b := make([]byte, 128)
+	var skipped int
+	n, _, _, err := p.ReadFromFiltered(b, func(n int, cm *ipv4.ControlMessage, src net.Addr) bool {
+		if string(b[:n]) == "SKIP" {
+			skipped++
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFromFiltered failed: %v", err)
+	}
+	if string(b[:n]) != "HELLO-R-U-THERE" || skipped != 2 {
+		t.Fatalf("got %q after skipping %v datagrams; expected %q after skipping 2", b[:n], skipped, "HELLO-R-U-THERE")
+	}
+
+	// The deadline applies to the whole call.
+	if err := p.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	if _, err := p.WriteTo([]byte("SKIP"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	_, _, _, err = p.ReadFromFiltered(b, func(int, *ipv4.ControlMessage, net.Addr) bool { return false })
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+}
+
+func TestPacketConnServe(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	wbs := []string{"HELLO", "HELLO-R-U-THERE", "STOP"}
+	for _, s := range wbs {
+		if _, err := p.WriteTo([]byte(s), nil, c.LocalAddr()); err != nil {
+			t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+		}
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	errStop := errors.New("stop")
+	var rbs []string
+	err = p.Serve(128, func(n int, cm *ipv4.ControlMessage, addr net.Addr, buf []byte) error {
+		if n != len(buf) {
+			t.Errorf("got %v; expected %v", n, len(buf))
+		}
+		rbs = append(rbs, string(buf))
+		if string(buf) == "STOP" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("got %v; expected %v", err, errStop)
+	}
+	if !reflect.DeepEqual(rbs, wbs) {
+		t.Fatalf("got %q; expected %q", rbs, wbs)
+	}
+
+	// A read failure stops the loop.
+	if err := p.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	err = p.Serve(128, func(int, *ipv4.ControlMessage, net.Addr, []byte) error { return nil })
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+}
+
+func TestPacketConnRingReader(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	r := p.NewRingReader(1, 128)
+
+	if _, err := p.WriteTo([]byte("HELLO"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	buf, _, addr, release, err := r.Next()
+	if err != nil {
+		t.Fatalf("ipv4.RingReader.Next failed: %v", err)
+	}
+	if string(buf) != "HELLO" || addr.String() != c.LocalAddr().String() {
+		t.Fatalf("got %q from %v; expected %q from %v", buf, addr, "HELLO", c.LocalAddr())
+	}
+
+	// The only slot is held, so the datagrams are dropped.
+	for i := 0; i < 2; i++ {
+		if _, err := p.WriteTo([]byte("DROP"), nil, c.LocalAddr()); err != nil {
+			t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+		}
+	}
+	for deadline := time.Now().Add(time.Second); r.Dropped() < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v dropped; expected 2", r.Dropped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	release()
+
+	// Releasing the slot again has no effect; handing the only slot
+	// over twice would block.
+	done := make(chan struct{})
+	go func() {
+		release()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("second release blocks")
+	}
+
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	buf, _, _, release, err = r.Next()
+	if err != nil {
+		t.Fatalf("ipv4.RingReader.Next failed: %v", err)
+	}
+	if string(buf) != "HELLO-R-U-THERE" {
+		t.Fatalf("got %q; expected %q", buf, "HELLO-R-U-THERE")
+	}
+	release()
+
+	// A read failure stops the reader.
+	_, _, _, _, err = r.Next()
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+	if v := r.Dropped(); v != 2 {
+		t.Fatalf("got %v dropped; expected 2", v)
+	}
+}
+
+func TestPacketConnWriteToRetry(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	policy := ipv4.RetryPolicy{MaxRetries: 3, InitialDelay: time.Millisecond}
+	if _, err := p.WriteToRetry([]byte("HELLO"), nil, c.LocalAddr(), ipv4.RetryPolicy{MaxRetries: -1}); err != syscall.EINVAL {
+		t.Fatalf("got %v; expected %v", err, syscall.EINVAL)
+	}
+	if n, err := p.WriteToRetry([]byte("HELLO"), nil, c.LocalAddr(), policy); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteToRetry failed: %v", err)
+	} else if n != len("HELLO") {
+		t.Fatalf("got %v; expected %v", n, len("HELLO"))
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	if n, _, _, err := p.ReadFrom(rb); err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	} else if string(rb[:n]) != "HELLO" {
+		t.Fatalf("got %q; expected %q", rb[:n], "HELLO")
+	}
+
+	// A permanent error is returned without retries.
+	policy = ipv4.RetryPolicy{InitialDelay: time.Hour}
+	dst := &net.UDPAddr{IP: net.IPv6loopback, Port: c.LocalAddr().(*net.UDPAddr).Port}
+	if _, err := p.WriteToRetry([]byte("HELLO"), nil, dst, policy); err == nil {
+		t.Fatal("ipv4.PacketConn.WriteToRetry succeeded for IPv6 destination")
+	}
+}
+
+func TestPacketConnReadReply(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if err := p.SetControlMessage(ipv4.FlagDst|ipv4.FlagInterface, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+	cc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer cc.Close()
+
+	for _, s := range []string{"IGNORE", "HELLO-R-U-THERE"} {
+		if _, err := cc.WriteTo([]byte(s), c.LocalAddr()); err != nil {
+			t.Fatalf("net.PacketConn.WriteTo failed: %v", err)
+		}
+	}
+	if err := p.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetDeadline failed: %v", err)
+	}
+	if err := cc.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("net.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	b := make([]byte, 128)
+	echo := func(req []byte, cm *ipv4.ControlMessage, from net.Addr) ([]byte, net.Addr, error) {
+		if string(req) == "IGNORE" {
+			return nil, nil, nil
+		}
+		return append([]byte("RE:"), req...), nil, nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.ReadReply(b, echo); err != nil {
+			t.Fatalf("ipv4.PacketConn.ReadReply failed: %v", err)
+		}
+	}
+	rb := make([]byte, 128)
+	n, from, err := cc.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("net.PacketConn.ReadFrom failed: %v", err)
+	}
+	if string(rb[:n]) != "RE:HELLO-R-U-THERE" || from.String() != c.LocalAddr().String() {
+		t.Fatalf("got %q from %v; expected %q from %v", rb[:n], from, "RE:HELLO-R-U-THERE", c.LocalAddr())
+	}
+}
+
+func TestPacketConnWriteBatchResults(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	ms := []ipv4.Message{
+		{Buffers: [][]byte{[]byte("HELLO-R-U-THERE")}, Addr: c.LocalAddr()},
+		{Buffers: [][]byte{[]byte("HELLO")}}, // no destination
+		{Buffers: [][]byte{[]byte("HELLO-"), []byte("R-U-THERE")}, Addr: c.LocalAddr()},
+	}
+	errs, err := p.WriteBatchResults(ms, 0)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteBatchResults failed: %v", err)
+	}
+	if len(errs) != len(ms) {
+		t.Fatalf("got %v results; expected %v", len(errs), len(ms))
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Fatalf("got %v; expected only the second message to fail", errs)
+	}
+	if ms[0].N != 15 || ms[1].N != 0 || ms[2].N != 15 {
+		t.Fatalf("got %v, %v, %v bytes written; expected 15, 0, 15", ms[0].N, ms[1].N, ms[2].N)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	for i := 0; i < 2; i++ {
+		n, _, _, err := p.ReadFrom(rb)
+		if err != nil {
+			t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+		}
+		if string(rb[:n]) != "HELLO-R-U-THERE" {
+			t.Fatalf("got %q; expected %q", rb[:n], "HELLO-R-U-THERE")
+		}
+	}
+	if _, err := p.WriteBatchResults(ms, 1); err == nil {
+		t.Fatal("ipv4.PacketConn.WriteBatchResults succeeded with non-zero flags")
+	}
+}
+
+func TestListenICMPWithID(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	const id = 0xbeef
+	if runtime.GOOS != "linux" {
+		if _, err := ipv4.ListenICMPWithID("udp4", "127.0.0.1", id); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if _, err := ipv4.ListenICMPWithID("ip4:icmp", "127.0.0.1", id); err == nil {
+		t.Fatal("ipv4.ListenICMPWithID succeeded with unknown network")
+	}
+	if _, err := ipv4.ListenICMPWithID("udp4", "127.0.0.1", 0x10000); err == nil {
+		t.Fatal("ipv4.ListenICMPWithID succeeded with out-of-range identifier")
+	}
+	p, err := ipv4.ListenICMPWithID("udp4", "127.0.0.1", id)
+	if err != nil {
+		t.Skipf("ipv4.ListenICMPWithID failed: %v", err)
+	}
+	defer p.Close()
+	if _, err := ipv4.ListenICMPWithID("udp4", "127.0.0.1", id); err == nil {
+		t.Fatal("ipv4.ListenICMPWithID succeeded with identifier in use")
+	}
+
+	wb, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{
+			ID: 0, Seq: 1, // the protocol stack fills in the identifier
+			Data: []byte("HELLO-R-U-THERE"),
+		},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatalf("icmp.Message.Marshal failed: %v", err)
+	}
+	if _, err := p.WriteTo(wb, nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	n, _, _, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	m, err := icmp.ParseMessage(iana.ProtocolICMP, rb[:n])
+	if err != nil {
+		t.Fatalf("icmp.ParseMessage failed: %v", err)
+	}
+	if echo, ok := m.Body.(*icmp.Echo); !ok || m.Type != ipv4.ICMPTypeEchoReply || echo.ID != id {
+		t.Fatalf("got %+v; expected echo reply with identifier %v", m, id)
+	}
+}
+
+func TestPacketConnLocalUDPAddr(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	a, err := p.LocalUDPAddr()
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.LocalUDPAddr failed: %v", err)
+	}
+	if a.String() != c.LocalAddr().String() {
+		t.Fatalf("got %v; expected %v", a, c.LocalAddr())
+	}
+
+	if os.Getuid() != 0 {
+		return
+	}
+	c, err = net.ListenPacket("ip4:icmp", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := ipv4.NewPacketConn(c).LocalUDPAddr(); err != ipv4.ErrInvalidConnType {
+		t.Fatalf("got %v; expected %v", err, ipv4.ErrInvalidConnType)
+	}
+}
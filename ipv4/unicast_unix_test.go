@@ -0,0 +1,93 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ipv4_test
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/ipv4"
+)
+
+// syscallErrno returns the errno held by err, an error returned by
+// the ReadFrom or WriteTo methods, in the form documented by the
+// package.
+func syscallErrno(t *testing.T, err error) syscall.Errno {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		t.Fatalf("got %#v; expected *net.OpError", err)
+	}
+	err = oe.Err
+	if me, ok := err.(*ipv4.MTUError); ok {
+		err = me.Err
+	}
+	serr, ok := err.(*os.SyscallError)
+	if !ok {
+		t.Fatalf("got %#v; expected *os.SyscallError", oe.Err)
+	}
+	errno, ok := serr.Err.(syscall.Errno)
+	if !ok {
+		t.Fatalf("got %#v; expected syscall.Errno", serr.Err)
+	}
+	return errno
+}
+
+func TestPacketConnWriteToErrno(t *testing.T) {
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	// A payload that doesn't fit in a single IPv4 datagram.
+	_, err = p.WriteTo(make([]byte, 1<<16), nil, c.LocalAddr())
+	if errno := syscallErrno(t, err); errno != syscall.EMSGSIZE {
+		t.Fatalf("got %v; expected %v", errno, syscall.EMSGSIZE)
+	}
+}
+
+func TestRawConnWriteToErrno(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	r, err := ipv4.NewRawConn(c)
+	if err != nil {
+		t.Fatalf("ipv4.NewRawConn failed: %v", err)
+	}
+
+	// Sending to the limited broadcast address requires
+	// SO_BROADCAST, which the net package enables on datagram and
+	// raw sockets.
+	if err := r.SetBroadcast(false); err != nil {
+		t.Fatalf("ipv4.RawConn.SetBroadcast failed: %v", err)
+	}
+	wh := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + 8,
+		TTL:      1,
+		Protocol: iana.ProtocolICMP,
+		Dst:      net.IPv4bcast,
+	}
+	err = r.WriteTo(wh, make([]byte, 8), nil)
+	if err == nil {
+		t.Fatal("ipv4.RawConn.WriteTo succeeded")
+	}
+	if errno := syscallErrno(t, err); errno != syscall.EACCES {
+		t.Fatalf("got %v; expected %v", errno, syscall.EACCES)
+	}
+}
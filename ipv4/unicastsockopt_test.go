@@ -8,7 +8,10 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"golang.org/x/net/internal/iana"
 	"golang.org/x/net/internal/nettest"
@@ -136,3 +139,354 @@ func testUnicastSocketOptions(t *testing.T, c testIPv4UnicastConn) {
 		t.Fatalf("got unexpected TTL value %v; expected %v", v, ttl)
 	}
 }
+
+func TestPacketConnPriority(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	const prio = 6 // priorities 0 through 6 require no privilege
+	if runtime.GOOS != "linux" {
+		if err := p.SetPriority(prio); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if err := p.SetPriority(prio); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetPriority failed: %v", err)
+	}
+	if v, err := p.Priority(); err != nil {
+		t.Fatalf("ipv4.PacketConn.Priority failed: %v", err)
+	} else if v != prio {
+		t.Fatalf("got unexpected priority value %v; expected %v", v, prio)
+	}
+}
+
+func TestPacketConnMark(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	const mark = 0xcafe
+	if runtime.GOOS != "linux" {
+		if err := p.SetMark(mark); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	if os.Getuid() != 0 {
+		t.Skip("must be root")
+	}
+	if err := p.SetMark(mark); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetMark failed: %v", err)
+	}
+	if v, err := p.Mark(); err != nil {
+		t.Fatalf("ipv4.PacketConn.Mark failed: %v", err)
+	} else if v != mark {
+		t.Fatalf("got unexpected mark value %#x; expected %#x", v, mark)
+	}
+
+	// The per packet mark is passed as the SO_MARK control message.
+	wb := []byte("HELLO-R-U-THERE")
+	if _, err := p.WriteTo(wb, &ipv4.ControlMessage{Mark: mark + 1}, c.LocalAddr()); err != nil {
+		if oe, ok := err.(*net.OpError); ok {
+			if serr, ok := oe.Err.(*os.SyscallError); ok && serr.Err == syscall.EINVAL {
+				t.Skipf("ipv4.PacketConn.WriteTo failed: %v", err) // SO_MARK control message appeared in Linux 6.0
+			}
+		}
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	n, _, _, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if string(rb[:n]) != string(wb) {
+		t.Fatalf("got %q; expected %q", rb[:n], wb)
+	}
+	if v, err := p.Mark(); err != nil || v != mark {
+		t.Fatalf("got %#x, %v; expected %#x, <nil>", v, err, mark)
+	}
+}
+
+func TestPacketConnBroadcast(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	for _, on := range []bool{true, false} {
+		if err := p.SetBroadcast(on); err != nil {
+			t.Fatalf("ipv4.PacketConn.SetBroadcast failed: %v", err)
+		}
+		if v, err := p.Broadcast(); err != nil {
+			t.Fatalf("ipv4.PacketConn.Broadcast failed: %v", err)
+		} else if v != on {
+			t.Fatalf("got %v; expected %v", v, on)
+		}
+	}
+}
+
+func TestPacketConnDontRoute(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	if runtime.GOOS == "windows" {
+		if err := p.SetDontRoute(true); err != ipv4.ErrNotSupported {
+			t.Fatalf("got %v; expected %v", err, ipv4.ErrNotSupported)
+		}
+		return
+	}
+	for _, on := range []bool{true, false} {
+		if err := p.SetDontRoute(on); err != nil {
+			t.Fatalf("ipv4.PacketConn.SetDontRoute failed: %v", err)
+		}
+		if v, err := p.DontRoute(); err != nil {
+			t.Fatalf("ipv4.PacketConn.DontRoute failed: %v", err)
+		} else if v != on {
+			t.Fatalf("got %v; expected %v", v, on)
+		}
+	}
+}
+
+func TestNewPacketConnWithOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+
+	loopback := false
+	p, err := ipv4.NewPacketConnWithOptions(c, ipv4.Options{
+		ReadBuffer:        1 << 16,
+		TTL:               32,
+		MulticastTTL:      2,
+		MulticastLoopback: &loopback,
+		ControlFlags:      ipv4.FlagTTL,
+	})
+	if err != nil {
+		t.Fatalf("ipv4.NewPacketConnWithOptions failed: %v", err)
+	}
+	if v, err := p.TTL(); err != nil || v != 32 {
+		t.Fatalf("got %v, %v; expected 32, <nil>", v, err)
+	}
+	if v, err := p.MulticastTTL(); err != nil || v != 2 {
+		t.Fatalf("got %v, %v; expected 2, <nil>", v, err)
+	}
+	if v, err := p.MulticastLoopback(); err != nil || v {
+		t.Fatalf("got %v, %v; expected false, <nil>", v, err)
+	}
+	if cf := p.ControlMessageFlags(); cf != ipv4.FlagTTL {
+		t.Fatalf("got %v; expected %v", cf, ipv4.FlagTTL)
+	}
+
+	// FlagRawHeader is not supported on UDP endpoints.
+	p, err = ipv4.NewPacketConnWithOptions(c, ipv4.Options{TTL: 16, ControlFlags: ipv4.FlagRawHeader})
+	oe, ok := err.(*ipv4.OptionError)
+	if !ok || oe.Option != "ControlFlags" || oe.Err != ipv4.ErrNotSupported {
+		t.Fatalf("got %v; expected ControlFlags option error", err)
+	}
+	if v, err := p.TTL(); err != nil || v != 16 {
+		t.Fatalf("got %v, %v; expected 16, <nil>", v, err)
+	}
+}
+
+func TestPacketConnConcurrentSetTTL(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	const N = 8
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := p.SetTTL(i + 1); err != nil {
+					t.Errorf("ipv4.PacketConn.SetTTL failed: %v", err)
+					return
+				}
+				if err := p.SetTOS(iana.DiffServAF11); err != nil {
+					t.Errorf("ipv4.PacketConn.SetTOS failed: %v", err)
+					return
+				}
+				ttl, err := p.TTL()
+				if err != nil {
+					t.Errorf("ipv4.PacketConn.TTL failed: %v", err)
+					return
+				}
+				if ttl < 1 || ttl > N {
+					t.Errorf("got %v; expected 1 <= ttl <= %v", ttl, N)
+					return
+				}
+				if _, err := p.TOS(); err != nil {
+					t.Errorf("ipv4.PacketConn.TOS failed: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPacketConnSwap(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c1, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c1.Close()
+	c2, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c2.Close()
+	p := ipv4.NewPacketConn(c1)
+	if err := p.SetTTL(42); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetTTL failed: %v", err)
+	}
+	cf := p.SupportedControlFlags() & (ipv4.FlagTTL | ipv4.FlagDst)
+	if err := p.SetControlMessage(cf, true); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+	}
+
+	old, err := p.Swap(c2)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.Swap failed: %v", err)
+	}
+	if old != c1 {
+		t.Fatalf("got %v; expected %v", old, c1)
+	}
+	if _, _, err := old.ReadFrom(make([]byte, 1)); err == nil {
+		t.Fatal("read on previous transport succeeded")
+	} else if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; expected timeout error", err)
+	}
+	if v, err := ipv4.NewPacketConn(c2).TTL(); err != nil || v != 42 {
+		t.Fatalf("got %v, %v; expected 42, <nil>", v, err)
+	}
+	if v := p.ControlMessageFlags(); v != cf {
+		t.Fatalf("got %#x; expected %#x", v, cf)
+	}
+	if _, err := p.WriteTo([]byte("HELLO-R-U-THERE"), nil, c2.LocalAddr()); err != nil {
+		t.Fatalf("ipv4.PacketConn.WriteTo failed: %v", err)
+	}
+	if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ipv4.PacketConn.SetReadDeadline failed: %v", err)
+	}
+	rb := make([]byte, 128)
+	_, rcm, _, err := p.ReadFrom(rb)
+	if err != nil {
+		t.Fatalf("ipv4.PacketConn.ReadFrom failed: %v", err)
+	}
+	if cf&ipv4.FlagTTL != 0 && (rcm == nil || rcm.TTL != 42) {
+		t.Fatalf("got %v; expected TTL 42", rcm)
+	}
+}
+
+func TestPacketConnSwapConcurrentRead(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "solaris", "windows":
+		t.Skipf("not supported on %q", runtime.GOOS)
+	}
+
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket failed: %v", err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b := make([]byte, 128)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			p.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+			p.ReadFrom(b)
+			if _, err := p.TTL(); err != nil {
+				t.Errorf("ipv4.PacketConn.TTL failed: %v", err)
+				return
+			}
+			if err := p.SetControlMessage(ipv4.FlagTTL, true); err != nil {
+				t.Errorf("ipv4.PacketConn.SetControlMessage failed: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 8; i++ {
+		nc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Errorf("net.ListenPacket failed: %v", err)
+			break
+		}
+		defer nc.Close()
+		if _, err := p.Swap(nc); err != nil {
+			t.Errorf("ipv4.PacketConn.Swap failed: %v", err)
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+}
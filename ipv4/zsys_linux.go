@@ -26,6 +26,7 @@ const (
 	sysIP_MINTTL          = 0x15
 	sysIP_NODEFRAG        = 0x16
 	sysIP_UNICAST_IF      = 0x32
+	sysIP_RECVFRAGSIZE    = 0x19
 
 	sysIP_MULTICAST_IF           = 0x20
 	sysIP_MULTICAST_TTL          = 0x21
@@ -45,6 +46,21 @@ const (
 	sysIP_PMTUDISC_INTERFACE = 0x4
 	sysIP_PMTUDISC_OMIT      = 0x5
 
+	sysICMP_FILTER = 0x1
+
+	sysSO_INCOMING_CPU = 0x31
+	sysSO_TXTIME       = 0x3d
+	sysSCM_TXTIME      = 0x3d
+	sysSO_MEMINFO      = 0x37
+
+	sysSK_MEMINFO_DROPS = 0x8
+	sysSK_MEMINFO_VARS  = 0x9
+
+	sysUDP_SEGMENT = 0x67
+	sysUDP_GRO     = 0x68
+
+	sysSKF_NET_OFF = -0x100000
+
 	sysSO_EE_ORIGIN_NONE         = 0x0
 	sysSO_EE_ORIGIN_LOCAL        = 0x1
 	sysSO_EE_ORIGIN_ICMP         = 0x2
@@ -58,6 +74,10 @@ const (
 	sysSizeofIPMreq       = 0x8
 	sysSizeofIPMreqn      = 0xc
 	sysSizeofIPMreqSource = 0xc
+
+	sysSizeofICMPFilter = 0x4
+
+	sysSizeofSockTxtime = 0x8
 )
 
 type sysInetPktinfo struct {
@@ -92,3 +112,12 @@ type sysIPMreqSource struct {
 	Interface  uint32
 	Sourceaddr uint32
 }
+
+type sysICMPFilter struct {
+	Data uint32
+}
+
+type sysSockTxtime struct {
+	Clockid int32
+	Flags   uint32
+}